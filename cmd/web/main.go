@@ -4,6 +4,7 @@ package main
 import (
 	"context"
 	"log"
+	"os"
 	"simple-finance/internal/app"
 )
 
@@ -37,6 +38,15 @@ import (
 func main() {
 	ctx := context.Background()
 
+	// web migrate up|down|status|version
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := app.Migrate(ctx, os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	app, err := app.NewApp(ctx)
 
 	if err != nil {
@@ -6,22 +6,53 @@ import (
 	"github.com/go-chi/cors"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
 	"net/http"
+	"simple-finance/internal/config"
 	"simple-finance/internal/handler"
 	"simple-finance/internal/handler/middleware"
+	"simple-finance/internal/models"
+	"simple-finance/internal/tokens"
 )
 
 type Router struct {
 	transactionHandler *handler.TransactionHandler
 	authHandler        *handler.AuthHandler
+	profileHandler     *handler.ProfileHandler
+	adminHandler       *handler.AdminHandler
+	ledgerHandler      *handler.LedgerHandler
 	authMiddleware     *middleware.AuthMiddleware
+	verifiedMiddleware *middleware.VerifiedEmailMiddleware
+	rateLimit          *middleware.RateLimitMiddleware
+	idempotency        *middleware.IdempotencyMiddleware
+	httpConfig         config.HTTPConfig
+	rateLimitConfig    config.RateLimitConfig
 	router             *chi.Mux
 }
 
-func NewRouter(h *handler.AuthHandler, t *handler.TransactionHandler, m *middleware.AuthMiddleware) *Router {
+func NewRouter(
+	h *handler.AuthHandler,
+	t *handler.TransactionHandler,
+	p *handler.ProfileHandler,
+	a *handler.AdminHandler,
+	l *handler.LedgerHandler,
+	m *middleware.AuthMiddleware,
+	v *middleware.VerifiedEmailMiddleware,
+	rl *middleware.RateLimitMiddleware,
+	i *middleware.IdempotencyMiddleware,
+	cfg config.HTTPConfig,
+	rlCfg config.RateLimitConfig,
+) *Router {
 	r := &Router{
 		transactionHandler: t,
 		authHandler:        h,
+		profileHandler:     p,
+		adminHandler:       a,
+		ledgerHandler:      l,
 		authMiddleware:     m,
+		verifiedMiddleware: v,
+		rateLimit:          rl,
+		idempotency:        i,
+		httpConfig:         cfg,
+		rateLimitConfig:    rlCfg,
 		router:             chi.NewRouter(),
 	}
 
@@ -37,11 +68,14 @@ func (r *Router) setupMiddleware() {
 	r.router.Use(m.Recoverer)
 	r.router.Use(m.RealIP)
 	r.router.Use(m.RequestID)
+	r.router.Use(middleware.LimitBody(r.httpConfig.MaxBodyBytes(), map[string]int64{
+		"/api/transaction/batch": r.httpConfig.MaxBatchBodyBytes(),
+	}))
 	r.router.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"https://*", "http://*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "Idempotency-Key", "If-Match", "If-None-Match"},
+		ExposedHeaders:   []string{"Link", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Idempotent-Replayed", "ETag"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -50,20 +84,125 @@ func (r *Router) setupMiddleware() {
 func (r *Router) setupRoutes() {
 
 	r.router.Route("/api", func(router chi.Router) {
+		router.Use(middleware.Timeout(r.httpConfig.APITimeout()))
+		router.Use(r.rateLimit.ByIP("api", r.rateLimitConfig.API()))
 		router.Use(r.authMiddleware.MakeAuth)
+		router.Use(r.rateLimit.ByUser("api", r.rateLimitConfig.API()))
+		router.Use(middleware.AuditMeta)
+		router.Use(r.idempotency.Handle)
+
+		// Профиль доступен и без подтверждённого email, чтобы его можно было исправить.
+		router.With(middleware.RequireScope(tokens.ResourceProfile)).Get("/me", r.profileHandler.GetMe)
+
+		// Управление аккаунтом доступно только из обычной сессии, не по API-токену.
+		router.Group(func(router chi.Router) {
+			router.Use(middleware.RequireScope(tokens.ResourceAccount))
+
+			router.Patch("/me", r.profileHandler.UpdateMe)
+			router.Delete("/me", r.profileHandler.DeleteMe)
+			router.Put("/me/password", r.authHandler.ChangePassword)
+			router.Post("/me/verify_email", r.profileHandler.ResendVerification)
+			router.Post("/me/2fa", r.authHandler.EnrollMFA)
+			router.Post("/me/2fa/confirm", r.authHandler.ConfirmMFA)
+			router.Delete("/me/2fa", r.authHandler.DisableMFA)
+			router.Post("/me/tokens", r.authHandler.CreateAPIToken)
+			router.Get("/me/tokens", r.authHandler.GetAPITokens)
+			router.Delete("/me/tokens/{token_id}", r.authHandler.RevokeAPIToken)
+			router.Get("/sessions", r.authHandler.GetSessions)
+			router.Delete("/sessions/{session_id}", r.authHandler.RevokeSession)
+			router.Get("/audit", r.profileHandler.GetAuditLog)
+		})
+
+		router.Group(func(router chi.Router) {
+			router.Use(r.verifiedMiddleware.RequireVerified)
+
+			router.Group(func(router chi.Router) {
+				router.Use(middleware.RequireScope(tokens.ResourceTransactions))
+
+				router.Post("/transaction", r.transactionHandler.InsertTransaction)
+				router.Get("/transaction", r.transactionHandler.GetTransactions)
+				router.Get("/transaction/suggest", r.transactionHandler.SuggestCategories)
+				router.Post("/transaction/batch", r.transactionHandler.InsertTransactions)
+				router.Patch("/transaction/batch", r.transactionHandler.UpdateTransactions)
+				router.Post("/transaction/batch/delete", r.transactionHandler.DeleteTransactions)
+				router.Get("/transaction/{transaction_uuid}", r.transactionHandler.GetTransactionByID)
+				router.Patch("/transaction/{transaction_uuid}", r.transactionHandler.UpdateTransaction)
+				router.Delete("/transaction/{transaction_uuid}", r.transactionHandler.DeleteTransactionByID)
+				router.Post("/transaction/{transaction_uuid}/restore", r.transactionHandler.RestoreTransaction)
+				router.Get("/trash", r.transactionHandler.GetTrash)
+				router.Get("/search", r.transactionHandler.Search)
 
-		router.Post("/transaction", r.transactionHandler.InsertTransaction)
-		router.Get("/transaction", r.transactionHandler.GetTransactions)
-		router.Get("/transaction/{transaction_uuid}", r.transactionHandler.GetTransactionByID)
-		router.Delete("/transaction/{transaction_uuid}", r.transactionHandler.DeleteTransactionByID)
+				router.Get("/ledgers", r.ledgerHandler.GetLedgers)
+				router.Get("/ledgers/{ledger_id}/categories", r.ledgerHandler.GetCategories)
+				router.Post("/ledgers/{ledger_id}/categories", r.ledgerHandler.InsertCategory)
+				router.Delete("/ledgers/{ledger_id}/categories/{category_id}", r.ledgerHandler.DeleteCategory)
+				router.Post("/ledgers/{ledger_id}/categories/{category_id}/restore", r.ledgerHandler.RestoreCategory)
+				router.Get("/ledgers/{ledger_id}/rules", r.ledgerHandler.GetRules)
+				router.Post("/ledgers/{ledger_id}/rules", r.ledgerHandler.CreateRule)
+				router.Post("/ledgers/{ledger_id}/rules/test", r.ledgerHandler.TestRule)
+				router.Post("/ledgers/{ledger_id}/rules/apply", r.ledgerHandler.ApplyRules)
+				router.Put("/ledgers/{ledger_id}/rules/{rule_id}", r.ledgerHandler.UpdateRule)
+				router.Delete("/ledgers/{ledger_id}/rules/{rule_id}", r.ledgerHandler.DeleteRule)
+				router.Get("/ledgers/{ledger_id}/goals", r.ledgerHandler.GetGoals)
+				router.Post("/ledgers/{ledger_id}/goals", r.ledgerHandler.CreateGoal)
+				router.Get("/ledgers/{ledger_id}/goals/{goal_id}", r.ledgerHandler.GetGoal)
+				router.Put("/ledgers/{ledger_id}/goals/{goal_id}", r.ledgerHandler.UpdateGoal)
+				router.Delete("/ledgers/{ledger_id}/goals/{goal_id}", r.ledgerHandler.DeleteGoal)
+				router.Get("/ledgers/{ledger_id}/goals/{goal_id}/contributions", r.ledgerHandler.GetContributions)
+				router.Post("/ledgers/{ledger_id}/goals/{goal_id}/contributions", r.ledgerHandler.AddContribution)
+				router.Delete("/ledgers/{ledger_id}/goals/{goal_id}/contributions/{contribution_id}", r.ledgerHandler.DeleteContribution)
+				router.Post("/ledgers/{ledger_id}/goals/{goal_id}/contributions/{contribution_id}/restore", r.ledgerHandler.RestoreContribution)
+			})
 
-		router.Get("/profile/{id}", r.transactionHandler.GetProfileHandler)
+			// Состав участников и приглашения меняются только из обычной сессии.
+			router.Group(func(router chi.Router) {
+				router.Use(middleware.RequireScope(tokens.ResourceAccount))
+
+				router.Post("/ledgers", r.ledgerHandler.CreateLedger)
+				router.Patch("/ledgers/{ledger_id}", r.ledgerHandler.RenameLedger)
+				router.Delete("/ledgers/{ledger_id}", r.ledgerHandler.DeleteLedger)
+				router.Get("/ledgers/{ledger_id}/members", r.ledgerHandler.GetMembers)
+				router.Put("/ledgers/{ledger_id}/members/{user_id}", r.ledgerHandler.SetMemberRole)
+				router.Delete("/ledgers/{ledger_id}/members/{user_id}", r.ledgerHandler.RemoveMember)
+				router.Post("/ledgers/{ledger_id}/invitations", r.ledgerHandler.Invite)
+				router.Get("/invitations", r.ledgerHandler.GetInvitations)
+				router.Post("/invitations/{invitation_id}/accept", r.ledgerHandler.AcceptInvitation)
+				router.Delete("/invitations/{invitation_id}", r.ledgerHandler.DeclineInvitation)
+			})
+
+			router.With(middleware.RequireScope(tokens.ResourceProfile)).Get("/profile/{id}", r.profileHandler.GetProfile)
+		})
+	})
+
+	r.router.Route("/admin", func(router chi.Router) {
+		router.Use(middleware.Timeout(r.httpConfig.APITimeout()))
+		router.Use(r.rateLimit.ByIP("api", r.rateLimitConfig.API()))
+		router.Use(r.authMiddleware.MakeAuth)
+		router.Use(middleware.RequireScope(tokens.ResourceAccount))
+		router.Use(middleware.RequireRole(models.RoleAdmin))
+		router.Use(middleware.AuditMeta)
+		router.Use(r.idempotency.Handle)
+
+		router.Get("/users", r.adminHandler.GetUsers)
+		router.Post("/users/{id}/disable", r.adminHandler.DisableUser)
+		router.Post("/users/{id}/enable", r.adminHandler.EnableUser)
+		router.Put("/users/{id}/role", r.adminHandler.SetUserRole)
+		router.Post("/users/{id}/reset_password", r.adminHandler.ResetUserPassword)
+		router.Get("/stats", r.adminHandler.GetStats)
 	})
 
 	r.router.Route("/auth", func(router chi.Router) {
-		router.Post("/sign_in", r.authHandler.SignIn)
+		router.Use(middleware.Timeout(r.httpConfig.AuthTimeout()))
+		router.Use(r.rateLimit.ByIP("auth", r.rateLimitConfig.Auth()))
+		router.Use(middleware.AuditMeta)
+
+		router.With(r.rateLimit.ByIP("sign_in", r.rateLimitConfig.SignIn())).Post("/sign_in", r.authHandler.SignIn)
+		router.With(r.rateLimit.ByIP("sign_in", r.rateLimitConfig.SignIn())).Post("/sign_in/mfa", r.authHandler.SignInMFA)
 		router.Post("/sign_up", r.authHandler.SignUp)
 		router.Post("/refresh/tokens", r.authHandler.RefreshTokens)
+		router.Get("/verify_email", r.profileHandler.VerifyEmail)
+		router.Post("/forgot_password", r.authHandler.ForgotPassword)
+		router.Post("/reset_password", r.authHandler.ResetPassword)
 	})
 	r.router.Mount("/swagger/", httpSwagger.WrapHandler)
 }
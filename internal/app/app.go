@@ -2,21 +2,21 @@ package app
 
 import (
 	"context"
-	"fmt"
 	"github.com/joho/godotenv"
 	"log"
 	"net/http"
 	_ "net/http"
 	"os"
+	"os/signal"
 	_ "simple-finance/docs"
 	"simple-finance/internal/api"
 	"simple-finance/internal/closer"
+	"syscall"
 )
 
 const (
-	signingKey    = "J9&#YAVu+gRY7S0V(j)M@8fbr}?$8t"
-	serverPortKey = "SERVER_PORT"
-	salt          = "dxetkyhvxkhpndxbfnmwkctqqekanrmq"
+	signingKey = "J9&#YAVu+gRY7S0V(j)M@8fbr}?$8t"
+	salt       = "dxetkyhvxkhpndxbfnmwkctqqekanrmq"
 )
 
 type App struct {
@@ -36,18 +36,42 @@ func NewApp(ctx context.Context) (*App, error) {
 }
 
 func (a *App) Run() error {
+	// Порядок важен: сначала перестаём принимать запросы, потом дожидаемся
+	// фоновых писем и только после этого закрываем базу и Redis.
 	defer func() {
+		err := a.serviceProvider.GetPasswordResetter().Close()
+		if err != nil {
+			log.Println("password resetter close failed:", err)
+		}
+
 		closer.CloseAll()
 		closer.Wait()
 	}()
 
-	return a.runHttpServer()
+	a.runTrashPurger()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- a.runHttpServer()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	return a.shutdownHttpServer()
 }
 
 func (a *App) initDeps(ctx context.Context) error {
 	inits := []func(context.Context) error{
 		a.initConfig,
 		a.initServiceProvider,
+		a.initMigrations,
 		a.initHttpServer}
 
 	for _, f := range inits {
@@ -73,23 +97,65 @@ func (a *App) initServiceProvider(_ context.Context) error {
 	return nil
 }
 
+func (a *App) initMigrations(ctx context.Context) error {
+	if !a.serviceProvider.GetPGConfig().AutoMigrate() {
+		return nil
+	}
+
+	return a.serviceProvider.GetMigrator().Up(ctx, 0)
+}
+
 func (a *App) initHttpServer(ctx context.Context) error {
-	router := api.NewRouter(a.serviceProvider.GetAuthHandler(), a.serviceProvider.GetTransactionHandler(), a.serviceProvider.GetAuthMiddleware())
+	httpConfig := a.serviceProvider.GetHTTPConfig()
+	router := api.NewRouter(
+		a.serviceProvider.GetAuthHandler(),
+		a.serviceProvider.GetTransactionHandler(),
+		a.serviceProvider.GetProfileHandler(),
+		a.serviceProvider.GetAdminHandler(),
+		a.serviceProvider.GetLedgerHandler(),
+		a.serviceProvider.GetAuthMiddleware(),
+		a.serviceProvider.GetVerifiedEmailMiddleware(),
+		a.serviceProvider.GetRateLimitMiddleware(),
+		a.serviceProvider.GetIdempotencyMiddleware(),
+		httpConfig,
+		a.serviceProvider.GetRateLimitConfig(),
+	)
 
-	serverPort := os.Getenv(serverPortKey)
-	if serverPort == "" {
-		log.Fatal("env var SERVER_PORT is empty")
-	}
-	addr := fmt.Sprintf(":%s", serverPort)
 	a.httpServer = &http.Server{
-		Addr:    addr,
-		Handler: router,
+		Addr:              httpConfig.Address(),
+		Handler:           router,
+		ReadTimeout:       httpConfig.ReadTimeout(),
+		ReadHeaderTimeout: httpConfig.ReadHeaderTimeout(),
+		WriteTimeout:      httpConfig.WriteTimeout(),
+		IdleTimeout:       httpConfig.IdleTimeout(),
 	}
 
 	return nil
 }
 
+// runTrashPurger purges the trash in the background until the app is closed.
+func (a *App) runTrashPurger() {
+	ctx, cancel := context.WithCancel(context.Background())
+	closer.Add(func() error {
+		cancel()
+		return nil
+	})
+
+	go a.serviceProvider.GetTrashPurger().Run(ctx)
+}
+
 func (a *App) runHttpServer() error {
 	log.Println("starting http server on port", a.httpServer.Addr)
 	return a.httpServer.ListenAndServe()
 }
+
+// shutdownHttpServer waits for requests in flight, at most as long as the
+// server may write a response.
+func (a *App) shutdownHttpServer() error {
+	log.Println("stopping http server")
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.serviceProvider.GetHTTPConfig().WriteTimeout())
+	defer cancel()
+
+	return a.httpServer.Shutdown(ctx)
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/joho/godotenv"
+	"simple-finance/internal/closer"
+)
+
+const migrateUsage = "usage: migrate up [N] | down [N|all] | status | version"
+
+// Migrate runs the migrate subcommand with the given arguments.
+func Migrate(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	err := godotenv.Load()
+	if err != nil {
+		return err
+	}
+
+	s := newServiceProvider()
+	defer func() {
+		closer.CloseAll()
+		closer.Wait()
+	}()
+
+	m := s.GetMigrator()
+
+	switch args[0] {
+	case "up":
+		steps, err := parseSteps(args[1:], 0)
+		if err != nil {
+			return err
+		}
+		return m.Up(ctx, steps)
+	case "down":
+		// Без аргумента откатываем только последнюю миграцию, чтобы случайно не снести всю схему.
+		steps, err := parseSteps(args[1:], 1)
+		if err != nil {
+			return err
+		}
+		return m.Down(ctx, steps)
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			state := "pending"
+			if st.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%06d_%s\t%s\n", st.Version, st.Name, state)
+		}
+		return nil
+	case "version":
+		version, dirty, err := m.Version(ctx)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			fmt.Printf("%d (dirty)\n", version)
+		} else {
+			fmt.Println(version)
+		}
+		return nil
+	default:
+		return errors.New(migrateUsage)
+	}
+}
+
+func parseSteps(args []string, def int) (int, error) {
+	if len(args) == 0 {
+		return def, nil
+	}
+	if args[0] == "all" {
+		return 0, nil
+	}
+
+	steps, err := strconv.Atoi(args[0])
+	if err != nil || steps <= 0 {
+		return 0, fmt.Errorf("invalid number of steps %q: %s", args[0], migrateUsage)
+	}
+
+	return steps, nil
+}
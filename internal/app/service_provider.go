@@ -2,44 +2,78 @@ package app
 
 import (
 	"context"
-	"github.com/go-playground/validator/v10"
 	"github.com/go-redis/redis/v8"
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sirupsen/logrus"
 	"log"
 	"simple-finance/internal/auth"
+	"simple-finance/internal/cache"
 	"simple-finance/internal/closer"
 	"simple-finance/internal/config"
 	"simple-finance/internal/db"
 	"simple-finance/internal/handler"
 	"simple-finance/internal/handler/middleware"
+	"simple-finance/internal/mailer"
+	"simple-finance/internal/migrator"
 	"simple-finance/internal/tokens"
+	"simple-finance/internal/trash"
+	"simple-finance/internal/validation"
+	"simple-finance/migrations"
+	"simple-finance/pkg/encrypt"
 	"simple-finance/pkg/hash"
 )
 
 type serviceProvider struct {
-	pgConfig config.PGConfig
+	pgConfig        config.PGConfig
+	httpConfig      config.HTTPConfig
+	mailConfig      config.MailConfig
+	rateLimitConfig config.RateLimitConfig
+	mfaConfig       config.MFAConfig
+	trashConfig     config.TrashConfig
 
-	conn *pgx.Conn
-	db   *db.FinanceDB
+	pool     *pgxpool.Pool
+	db       *db.FinanceDB
+	migrator *migrator.Migrator
 
 	logger *logrus.Logger
 
-	validate *validator.Validate
+	validate *validation.Validator
 	hasher   *hash.SHA1Hasher
 
 	tokenManager *tokens.TokenManager
 
-	auth *auth.Manager
+	auth          *auth.Manager
+	emailVerifier *auth.EmailVerifier
+	resetter      *auth.PasswordResetter
+	mfa           *auth.MFAManager
+	encryptor     encrypt.Encryptor
+
+	mailer mailer.Mailer
+
+	trashPurger *trash.Purger
 
 	//redisConfig redisConfig
-	redisClient *redis.Client
+	redisClient  *redis.Client
+	profileCache *cache.ProfileCache
+	revocations  *cache.TokenRevocations
+	rateLimiter  *cache.RateLimiter
+	lockout      *cache.LoginLockout
+	idempotency  *cache.IdempotencyStore
 
 	authHandler *handler.AuthHandler
 
 	transactionHandler *handler.TransactionHandler
 
-	authMiddleware *middleware.AuthMiddleware
+	profileHandler *handler.ProfileHandler
+
+	adminHandler *handler.AdminHandler
+
+	ledgerHandler *handler.LedgerHandler
+
+	authMiddleware        *middleware.AuthMiddleware
+	verifiedMiddleware    *middleware.VerifiedEmailMiddleware
+	rateLimitMiddleware   *middleware.RateLimitMiddleware
+	idempotencyMiddleware *middleware.IdempotencyMiddleware
 }
 
 type redisConfig interface {
@@ -63,6 +97,66 @@ func (s *serviceProvider) GetPGConfig() config.PGConfig {
 	return s.pgConfig
 }
 
+func (s *serviceProvider) GetHTTPConfig() config.HTTPConfig {
+	if s.httpConfig == nil {
+		cfg, err := config.NewHTTPConfig()
+		if err != nil {
+			log.Panicln("HTTP config error:", err)
+		}
+		s.httpConfig = cfg
+	}
+
+	return s.httpConfig
+}
+
+func (s *serviceProvider) GetMailConfig() config.MailConfig {
+	if s.mailConfig == nil {
+		cfg, err := config.NewMailConfig()
+		if err != nil {
+			log.Panicln("Mail config error:", err)
+		}
+		s.mailConfig = cfg
+	}
+
+	return s.mailConfig
+}
+
+func (s *serviceProvider) GetRateLimitConfig() config.RateLimitConfig {
+	if s.rateLimitConfig == nil {
+		cfg, err := config.NewRateLimitConfig()
+		if err != nil {
+			log.Panicln("Rate limit config error:", err)
+		}
+		s.rateLimitConfig = cfg
+	}
+
+	return s.rateLimitConfig
+}
+
+func (s *serviceProvider) GetMFAConfig() config.MFAConfig {
+	if s.mfaConfig == nil {
+		cfg, err := config.NewMFAConfig()
+		if err != nil {
+			log.Panicln("MFA config error:", err)
+		}
+		s.mfaConfig = cfg
+	}
+
+	return s.mfaConfig
+}
+
+func (s *serviceProvider) GetTrashConfig() config.TrashConfig {
+	if s.trashConfig == nil {
+		cfg, err := config.NewTrashConfig()
+		if err != nil {
+			log.Panicln("Trash config error:", err)
+		}
+		s.trashConfig = cfg
+	}
+
+	return s.trashConfig
+}
+
 func (s *serviceProvider) GetLogger() *logrus.Logger {
 	if s.logger == nil {
 		logger := logrus.New()
@@ -72,29 +166,38 @@ func (s *serviceProvider) GetLogger() *logrus.Logger {
 	return s.logger
 }
 
-func (s *serviceProvider) GetConn() *pgx.Conn {
+func (s *serviceProvider) GetPool() *pgxpool.Pool {
 
-	if s.conn == nil {
-		conn, err := pgx.Connect(context.Background(), s.GetPGConfig().DSN())
+	if s.pool == nil {
+		pool, err := pgxpool.New(context.Background(), s.GetPGConfig().DSN())
 		if err != nil {
 			log.Panicln(nil, "Database connection failed.", err)
 		}
 		closer.Add(func() error {
-			err := conn.Close(context.Background())
-			if err != nil {
-				return err
-			}
+			pool.Close()
 			return nil
 		})
-		s.conn = conn
+		s.pool = pool
 	}
 
-	return s.conn
+	return s.pool
+}
+
+func (s *serviceProvider) GetMigrator() *migrator.Migrator {
+	if s.migrator == nil {
+		m, err := migrator.New(s.GetPool(), migrations.FS)
+		if err != nil {
+			log.Panicln("Migrations loading failed.", err)
+		}
+		s.migrator = m
+	}
+
+	return s.migrator
 }
 
 func (s *serviceProvider) GetFinanceDb() *db.FinanceDB {
 	if s.db == nil {
-		s.db = db.NewFinanceDB(s.GetConn())
+		s.db = db.NewFinanceDB(s.GetPool())
 	}
 
 	return s.db
@@ -142,9 +245,13 @@ func (s *serviceProvider) GetHasher() *hash.SHA1Hasher {
 	return s.hasher
 }
 
-func (s *serviceProvider) GetValidator() *validator.Validate {
+func (s *serviceProvider) GetValidator() *validation.Validator {
 	if s.validate == nil {
-		s.validate = validator.New(validator.WithRequiredStructEnabled())
+		validate, err := validation.New()
+		if err != nil {
+			log.Panicln("Validator init failed.", err)
+		}
+		s.validate = validate
 	}
 
 	return s.validate
@@ -163,27 +270,193 @@ func (s *serviceProvider) GetTokenManager() *tokens.TokenManager {
 
 func (s *serviceProvider) GetAuthManager() *auth.Manager {
 	if s.auth == nil {
-		s.auth = auth.NewManager(s.GetFinanceDb(), s.GetHasher(), s.GetTokenManager())
+		cfg := s.GetRateLimitConfig()
+		// Блокировка логина для всех адресов сразу хранится отдельно и срабатывает позже.
+		loginLockout := cache.NewLoginLockout(s.GetRedisClient(), cfg.LockoutLoginThreshold(), cfg.LockoutBase(), cfg.LockoutMax())
+		s.auth = auth.NewManager(
+			s.GetFinanceDb(),
+			s.GetHasher(),
+			s.GetTokenManager(),
+			s.GetTokenRevocations(),
+			s.GetLoginLockout(),
+			loginLockout,
+			s.GetLogger(),
+		)
 	}
 	return s.auth
 }
 
+func (s *serviceProvider) GetMailer() mailer.Mailer {
+	if s.mailer == nil {
+		cfg := s.GetMailConfig()
+		switch cfg.Driver() {
+		case config.MailDriverSMTP:
+			s.mailer = mailer.NewSMTPMailer(cfg.SMTPHost(), cfg.SMTPPort(), cfg.SMTPUser(), cfg.SMTPPassword(), cfg.From())
+		case config.MailDriverFile:
+			m, err := mailer.NewFileMailer(cfg.Dir(), cfg.From().String())
+			if err != nil {
+				log.Panicln("File mailer init failed.", err)
+			}
+			s.mailer = m
+		default:
+			s.mailer = mailer.NewLogMailer(s.GetLogger())
+		}
+	}
+	return s.mailer
+}
+
+func (s *serviceProvider) GetEmailVerifier() *auth.EmailVerifier {
+	if s.emailVerifier == nil {
+		cfg := s.GetMailConfig()
+		s.emailVerifier = auth.NewEmailVerifier(s.GetFinanceDb(), s.GetTokenManager(), s.GetMailer(), cfg.BaseURL(), cfg.VerificationTTL())
+	}
+	return s.emailVerifier
+}
+
+func (s *serviceProvider) GetPasswordResetter() *auth.PasswordResetter {
+	if s.resetter == nil {
+		cfg := s.GetMailConfig()
+		s.resetter = auth.NewPasswordResetter(
+			s.GetFinanceDb(),
+			s.GetHasher(),
+			s.GetAuthManager(),
+			s.GetMailer(),
+			s.GetLogger(),
+			cfg.PasswordResetURL(),
+			cfg.PasswordResetTTL(),
+		)
+	}
+	return s.resetter
+}
+
+func (s *serviceProvider) GetEncryptor() encrypt.Encryptor {
+	if s.encryptor == nil {
+		encryptor, err := encrypt.NewAESEncryptor(s.GetMFAConfig().EncryptionKey())
+		if err != nil {
+			log.Panicln("Encryptor init failed.", err)
+		}
+		s.encryptor = encryptor
+	}
+	return s.encryptor
+}
+
+func (s *serviceProvider) GetMFAManager() *auth.MFAManager {
+	if s.mfa == nil {
+		cfg := s.GetMFAConfig()
+		s.mfa = auth.NewMFAManager(s.GetFinanceDb(), s.GetEncryptor(), s.GetTokenManager(), s.GetLoginLockout(), cfg.Issuer(), cfg.ChallengeTTL())
+	}
+	return s.mfa
+}
+
+func (s *serviceProvider) GetTrashPurger() *trash.Purger {
+	if s.trashPurger == nil {
+		cfg := s.GetTrashConfig()
+		s.trashPurger = trash.NewPurger(s.GetFinanceDb(), cfg.Retention(), cfg.PurgeInterval(), s.GetLogger())
+	}
+
+	return s.trashPurger
+}
+
 func (s *serviceProvider) GetAuthHandler() *handler.AuthHandler {
 	if s.authHandler == nil {
-		s.authHandler = handler.NewAuthHandler(s.GetValidator(), s.GetFinanceDb(), s.GetLogger(), s.GetHasher(), s.GetAuthManager())
+		s.authHandler = handler.NewAuthHandler(s.GetValidator(), s.GetFinanceDb(), s.GetLogger(), s.GetHasher(), s.GetAuthManager(), s.GetEmailVerifier(), s.GetPasswordResetter(), s.GetMFAManager())
 	}
 	return s.authHandler
 }
 func (s *serviceProvider) GetTransactionHandler() *handler.TransactionHandler {
 	if s.transactionHandler == nil {
-		s.transactionHandler = handler.NewTransactionHandler(s.GetFinanceDb(), s.GetValidator(), s.GetLogger(), s.GetRedisClient())
+		s.transactionHandler = handler.NewTransactionHandler(s.GetFinanceDb(), s.GetValidator(), s.GetLogger())
 	}
 	return s.transactionHandler
 }
 
+func (s *serviceProvider) GetProfileCache() *cache.ProfileCache {
+	if s.profileCache == nil {
+		s.profileCache = cache.NewProfileCache(s.GetRedisClient())
+	}
+	return s.profileCache
+}
+
+func (s *serviceProvider) GetTokenRevocations() *cache.TokenRevocations {
+	if s.revocations == nil {
+		s.revocations = cache.NewTokenRevocations(s.GetRedisClient())
+	}
+	return s.revocations
+}
+
+func (s *serviceProvider) GetRateLimiter() *cache.RateLimiter {
+	if s.rateLimiter == nil {
+		s.rateLimiter = cache.NewRateLimiter(s.GetRedisClient())
+	}
+	return s.rateLimiter
+}
+
+func (s *serviceProvider) GetIdempotencyStore() *cache.IdempotencyStore {
+	if s.idempotency == nil {
+		s.idempotency = cache.NewIdempotencyStore(s.GetRedisClient())
+	}
+	return s.idempotency
+}
+
+func (s *serviceProvider) GetLoginLockout() *cache.LoginLockout {
+	if s.lockout == nil {
+		cfg := s.GetRateLimitConfig()
+		s.lockout = cache.NewLoginLockout(s.GetRedisClient(), cfg.LockoutThreshold(), cfg.LockoutBase(), cfg.LockoutMax())
+	}
+	return s.lockout
+}
+
+func (s *serviceProvider) GetProfileHandler() *handler.ProfileHandler {
+	if s.profileHandler == nil {
+		s.profileHandler = handler.NewProfileHandler(s.GetFinanceDb(), s.GetValidator(), s.GetLogger(), s.GetProfileCache(), s.GetAuthManager(), s.GetEmailVerifier())
+	}
+	return s.profileHandler
+}
+
+func (s *serviceProvider) GetAdminHandler() *handler.AdminHandler {
+	if s.adminHandler == nil {
+		s.adminHandler = handler.NewAdminHandler(s.GetValidator(), s.GetLogger(), s.GetAuthManager(), s.GetPasswordResetter(), s.GetProfileCache())
+	}
+	return s.adminHandler
+}
+
+func (s *serviceProvider) GetLedgerHandler() *handler.LedgerHandler {
+	if s.ledgerHandler == nil {
+		s.ledgerHandler = handler.NewLedgerHandler(s.GetFinanceDb(), s.GetValidator(), s.GetLogger())
+	}
+	return s.ledgerHandler
+}
+
 func (s *serviceProvider) GetAuthMiddleware() *middleware.AuthMiddleware {
 	if s.authMiddleware == nil {
-		s.authMiddleware = middleware.NewAuthMiddleware(s.GetTokenManager())
+		s.authMiddleware = middleware.NewAuthMiddleware(s.GetAuthManager())
 	}
 	return s.authMiddleware
 }
+
+func (s *serviceProvider) GetVerifiedEmailMiddleware() *middleware.VerifiedEmailMiddleware {
+	if s.verifiedMiddleware == nil {
+		s.verifiedMiddleware = middleware.NewVerifiedEmailMiddleware(s.GetFinanceDb(), s.GetMailConfig().RequireVerifiedEmail())
+	}
+	return s.verifiedMiddleware
+}
+
+func (s *serviceProvider) GetRateLimitMiddleware() *middleware.RateLimitMiddleware {
+	if s.rateLimitMiddleware == nil {
+		s.rateLimitMiddleware = middleware.NewRateLimitMiddleware(s.GetRateLimiter(), s.GetLogger(), s.GetRateLimitConfig().Enabled())
+	}
+	return s.rateLimitMiddleware
+}
+
+func (s *serviceProvider) GetIdempotencyMiddleware() *middleware.IdempotencyMiddleware {
+	if s.idempotencyMiddleware == nil {
+		httpConfig := s.GetHTTPConfig()
+		s.idempotencyMiddleware = middleware.NewIdempotencyMiddleware(
+			s.GetIdempotencyStore(),
+			s.GetLogger(),
+			httpConfig.IdempotencyTTL(),
+			httpConfig.WriteTimeout(),
+		)
+	}
+	return s.idempotencyMiddleware
+}
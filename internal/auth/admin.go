@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+
+	"simple-finance/internal/errs"
+	"simple-finance/internal/models"
+)
+
+// SetUserDisabled disables or re-enables the account. Disabling signs the user
+// out everywhere, API tokens stop working while the account is disabled.
+func (m *Manager) SetUserDisabled(ctx context.Context, adminID, userID string, disabled bool) error {
+	if adminID == userID {
+		return errs.Conflict("cannot_modify_self", "administrators cannot change their own account this way")
+	}
+
+	err := m.db.SetUserDisabled(ctx, userID, disabled)
+	if err != nil {
+		return err
+	}
+
+	if !disabled {
+		return nil
+	}
+
+	return m.RevokeTokens(ctx, userID)
+}
+
+// SetUserRole changes the role of the user. The role is stored in tokens, so
+// the user's tokens are revoked and the new role applies after signing in again.
+func (m *Manager) SetUserRole(ctx context.Context, adminID, userID, role string) error {
+	if adminID == userID {
+		return errs.Conflict("cannot_modify_self", "administrators cannot change their own account this way")
+	}
+
+	err := m.db.SetUserRole(ctx, userID, role)
+	if err != nil {
+		return err
+	}
+
+	return m.RevokeTokens(ctx, userID)
+}
+
+func (m *Manager) SearchUsers(ctx context.Context, filter models.UserFilter) (models.UserList, error) {
+	return m.db.SearchUsers(ctx, filter)
+}
+
+func (m *Manager) SystemStats(ctx context.Context) (models.SystemStats, error) {
+	return m.db.GetSystemStats(ctx)
+}
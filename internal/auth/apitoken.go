@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"simple-finance/internal/errs"
+	"simple-finance/internal/models"
+	"simple-finance/internal/tokens"
+)
+
+// APITokenPrefix отличает персональные токены от JWT в заголовке Authorization
+// и помогает сканерам секретов находить случайно опубликованные токены.
+const APITokenPrefix = "sf_pat_"
+
+// CreateAPIToken issues a personal access token. The secret is returned only
+// here, the database keeps its hash.
+func (m *Manager) CreateAPIToken(ctx context.Context, userID string, input models.CreateAPITokenInput) (models.CreatedAPIToken, error) {
+	secret, err := newSecretToken()
+	if err != nil {
+		return models.CreatedAPIToken{}, err
+	}
+	token := APITokenPrefix + secret
+
+	apiToken, err := m.db.InsertAPIToken(ctx, userID, hashToken(token), models.APIToken{
+		ID:        uuid.New().String(),
+		Name:      input.Name,
+		Scopes:    input.Scopes,
+		ExpiresAt: input.ExpiresAt,
+	})
+	if err != nil {
+		return models.CreatedAPIToken{}, err
+	}
+
+	return models.CreatedAPIToken{APIToken: apiToken, Token: token}, nil
+}
+
+func (m *Manager) APITokens(ctx context.Context, userID string) ([]models.APIToken, error) {
+	return m.db.GetAPITokens(ctx, userID)
+}
+
+func (m *Manager) RevokeAPIToken(ctx context.Context, userID, tokenID string) error {
+	return m.db.DeleteAPIToken(ctx, userID, tokenID)
+}
+
+func isAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+func (m *Manager) parseAPIToken(ctx context.Context, token string) (tokens.TokenInfo, error) {
+	tokenInfo, err := m.db.UseAPIToken(ctx, hashToken(token))
+	if errors.Is(err, errs.ErrNotFound) {
+		return tokens.TokenInfo{}, fmt.Errorf("%w: unknown or expired api token", errs.ErrInvalidToken)
+	}
+
+	return tokenInfo, err
+}
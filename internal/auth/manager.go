@@ -2,69 +2,327 @@ package auth
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"simple-finance/internal/cache"
 	"simple-finance/internal/db"
 	"simple-finance/internal/errs"
+	"simple-finance/internal/models"
 	"simple-finance/internal/tokens"
 	"simple-finance/pkg/hash"
 )
 
+// revocationTTL должен быть не меньше времени жизни refresh токена.
+const revocationTTL = 60 * 24 * time.Hour
+
 type Manager struct {
 	db           *db.FinanceDB
 	hasher       hash.PasswordHasher
 	tokenManager *tokens.TokenManager
+	revocations  *cache.TokenRevocations
+	lockout      *cache.LoginLockout
+	loginLockout *cache.LoginLockout
+	logger       *logrus.Logger
 }
 
+// lockout counts failures per login and IP address, loginLockout per login
+// from all addresses with a higher threshold.
 func NewManager(
 	db *db.FinanceDB,
 	hasher hash.PasswordHasher,
 	tokenManager *tokens.TokenManager,
+	revocations *cache.TokenRevocations,
+	lockout *cache.LoginLockout,
+	loginLockout *cache.LoginLockout,
+	logger *logrus.Logger,
 ) *Manager {
 	return &Manager{
 		db:           db,
 		hasher:       hasher,
 		tokenManager: tokenManager,
+		revocations:  revocations,
+		lockout:      lockout,
+		loginLockout: loginLockout,
+		logger:       logger,
 	}
 }
 
-func (m *Manager) ComparePassword(ctx context.Context, userName, inputPass string) (string, error) {
+// ComparePassword checks the password of the user identified by login,
+// which is either a username or an email. New usernames cannot contain "@",
+// older ones still can, so they are tried when no email matches.
+// Repeated failures lock the login for a growing period of time, first for
+// the IP address they come from, then for everyone. Unknown logins are
+// counted the same way, so a lock does not tell whether an account exists.
+func (m *Manager) ComparePassword(ctx context.Context, login, ip, inputPass string) (string, error) {
+	ipKey, loginKey := lockoutKeys(login, ip)
+
+	lockedFor := m.lockedFor(ctx, ipKey, loginKey)
+	if lockedFor > 0 {
+		return "", accountLocked(lockedFor)
+	}
+
 	inputHashPass, err := m.hasher.Hash(inputPass)
 	if err != nil {
 		return "", err
 	}
 
-	userInfo, err := m.db.GetUserInfo(ctx, userName)
+	var userInfo models.UserInfo
+	if strings.Contains(login, "@") {
+		userInfo, err = m.db.GetUserInfoByEmail(ctx, login)
+	}
+	if !strings.Contains(login, "@") || errors.Is(err, errs.ErrNotFound) {
+		userInfo, err = m.db.GetUserInfo(ctx, login)
+	}
+	if err == nil && userInfo.Password != inputHashPass {
+		err = errs.ErrInvalidPassword
+	}
+	if errors.Is(err, errs.ErrNotFound) || errors.Is(err, errs.ErrInvalidPassword) {
+		lockedFor = m.fail(ctx, ipKey, loginKey)
+		if lockedFor > 0 {
+			return "", accountLocked(lockedFor)
+		}
+	}
 	if err != nil {
 		return "", err
 	}
 
-	if userInfo.Password != inputHashPass {
-		return "", errs.ErrInvalidPassword
-	}
+	m.resetLockout(ctx, ipKey, loginKey)
 
 	return userInfo.ID, nil
 }
 
-func (m *Manager) MakeTokens(userID string, accessTokenTTL, refreshTokenTTL time.Duration) (string, string, error) {
-	accessToken, err := m.tokenManager.NewJWT(tokens.TokenInfo{UserID: userID}, accessTokenTTL)
+// lockoutKeys возвращает ключи блокировки логина для одного адреса и для всех.
+func lockoutKeys(login, ip string) (string, string) {
+	login = strings.ToLower(strings.TrimSpace(login))
+
+	return "ip:" + ip + ":" + login, "login:" + login
+}
+
+// Redis для блокировки не обязателен: при его ошибках, как и в ограничителе
+// частоты запросов, вход не блокируем, а только пишем в лог.
+
+func (m *Manager) lockedFor(ctx context.Context, ipKey, loginKey string) time.Duration {
+	ipLock, err := m.lockout.LockedFor(ctx, ipKey)
+	if err != nil {
+		m.logger.Warn("sign-in lockout skipped: ", err)
+		return 0
+	}
+
+	loginLock, err := m.loginLockout.LockedFor(ctx, loginKey)
+	if err != nil {
+		m.logger.Warn("sign-in lockout skipped: ", err)
+		return 0
+	}
+
+	return max(ipLock, loginLock)
+}
+
+func (m *Manager) fail(ctx context.Context, ipKey, loginKey string) time.Duration {
+	ipLock, err := m.lockout.Fail(ctx, ipKey)
+	if err != nil {
+		m.logger.Warn("sign-in failure not counted: ", err)
+		return 0
+	}
+
+	loginLock, err := m.loginLockout.Fail(ctx, loginKey)
+	if err != nil {
+		m.logger.Warn("sign-in failure not counted: ", err)
+		return 0
+	}
+
+	return max(ipLock, loginLock)
+}
+
+func (m *Manager) resetLockout(ctx context.Context, ipKey, loginKey string) {
+	err := m.lockout.Reset(ctx, ipKey)
+	if err == nil {
+		err = m.loginLockout.Reset(ctx, loginKey)
+	}
+	if err != nil {
+		m.logger.Warn("sign-in lockout not reset: ", err)
+	}
+}
+
+func accountLocked(retryAfter time.Duration) error {
+	return errs.TooManyRequests("account_locked", "too many failed sign-in attempts, try again later", retryAfter)
+}
+
+// CheckPassword verifies the password of an already authenticated user.
+func (m *Manager) CheckPassword(ctx context.Context, userID, inputPass string) error {
+	inputHashPass, err := m.hasher.Hash(inputPass)
+	if err != nil {
+		return err
+	}
+
+	hashPass, err := m.db.GetPasswordHash(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if hashPass != inputHashPass {
+		return errs.ErrInvalidPassword
+	}
+
+	return nil
+}
+
+// ChangePassword sets a new password and revokes all tokens issued before,
+// so other devices have to sign in again.
+func (m *Manager) ChangePassword(ctx context.Context, userID, currentPass, newPass string) error {
+	err := m.CheckPassword(ctx, userID, currentPass)
+	if err != nil {
+		return err
+	}
+
+	newHashPass, err := m.hasher.Hash(newPass)
+	if err != nil {
+		return err
+	}
+
+	err = m.db.UpdatePassword(ctx, userID, newHashPass)
+	if err != nil {
+		return err
+	}
+
+	return m.RevokeTokens(ctx, userID)
+}
+
+// RevokeTokens invalidates every token of the user issued until now
+// and ends all sessions.
+func (m *Manager) RevokeTokens(ctx context.Context, userID string) error {
+	err := m.revocations.RevokeBefore(ctx, userID, time.Now(), revocationTTL)
+	if err != nil {
+		return err
+	}
+
+	return m.db.RevokeSessions(ctx, userID)
+}
+
+func (m *Manager) Sessions(ctx context.Context, userID string) ([]models.Session, error) {
+	return m.db.GetSessions(ctx, userID)
+}
+
+func (m *Manager) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	return m.db.RevokeSession(ctx, userID, sessionID)
+}
+
+// ParseToken parses the token and checks that it has not been revoked.
+// Both JWTs and personal API tokens are accepted.
+func (m *Manager) ParseToken(ctx context.Context, token string) (tokens.TokenInfo, error) {
+	if isAPIToken(token) {
+		return m.parseAPIToken(ctx, token)
+	}
+
+	tokenInfo, err := m.tokenManager.Parse(token)
+	if err != nil {
+		return tokens.TokenInfo{}, fmt.Errorf("%w: %v", errs.ErrInvalidToken, err)
+	}
+
+	revokedBefore, ok, err := m.revocations.RevokedBefore(ctx, tokenInfo.UserID)
+	if err != nil {
+		return tokens.TokenInfo{}, err
+	}
+
+	// iat хранится с точностью до секунды, поэтому токены, выпущенные
+	// в ту же секунду, что и отзыв, остаются действительными.
+	if ok && tokenInfo.IssuedAt.Before(revokedBefore) {
+		return tokens.TokenInfo{}, fmt.Errorf("%w: token revoked", errs.ErrInvalidToken)
+	}
+
+	if tokenInfo.SessionID != "" {
+		err = m.db.TouchSession(ctx, tokenInfo.SessionID)
+		if errors.Is(err, errs.ErrNotFound) {
+			return tokens.TokenInfo{}, fmt.Errorf("%w: session is not active", errs.ErrInvalidToken)
+		}
+		if err != nil {
+			return tokens.TokenInfo{}, err
+		}
+	}
+
+	return tokenInfo, nil
+}
+
+// MakeTokens starts a new session for the client and issues tokens bound to it.
+func (m *Manager) MakeTokens(ctx context.Context, userID string, client models.Session, accessTokenTTL, refreshTokenTTL time.Duration) (string, string, error) {
+	role, err := m.userRole(ctx, userID)
+	if err != nil {
+		return "", "", err
+	}
+
+	client.ID = uuid.New().String()
+
+	err = m.db.InsertSession(ctx, userID, client, time.Now().Add(refreshTokenTTL))
+	if err != nil {
+		return "", "", err
+	}
+
+	return m.issueTokens(tokens.TokenInfo{UserID: userID, Role: role, SessionID: client.ID}, accessTokenTTL, refreshTokenTTL)
+}
+
+// RefreshTokens issues new tokens for the session of the refresh token and
+// extends it. Tokens issued before sessions existed get a new session.
+func (m *Manager) RefreshTokens(ctx context.Context, refreshToken string, client models.Session, accessTokenTTL, refreshTokenTTL time.Duration) (string, string, error) {
+	// Иначе ограниченный по scope API-токен можно было бы обменять на полную сессию.
+	if isAPIToken(refreshToken) {
+		return "", "", fmt.Errorf("%w: api token cannot be refreshed", errs.ErrInvalidToken)
+	}
+
+	tokenInfo, err := m.ParseToken(ctx, refreshToken)
+	if err != nil {
+		return "", "", err
+	}
+
+	if tokenInfo.SessionID == "" {
+		return m.MakeTokens(ctx, tokenInfo.UserID, client, accessTokenTTL, refreshTokenTTL)
+	}
+
+	// Роль берём из базы, а не из старого токена: так её изменение
+	// применяется при следующем обновлении токенов.
+	role, err := m.userRole(ctx, tokenInfo.UserID)
 	if err != nil {
 		return "", "", err
 	}
 
-	refreshToken, err := m.tokenManager.NewJWT(tokens.TokenInfo{UserID: userID}, refreshTokenTTL)
+	err = m.db.ExtendSession(ctx, tokenInfo.SessionID, time.Now().Add(refreshTokenTTL))
+	if errors.Is(err, errs.ErrNotFound) {
+		return "", "", fmt.Errorf("%w: session is not active", errs.ErrInvalidToken)
+	}
 	if err != nil {
 		return "", "", err
 	}
 
-	return accessToken, refreshToken, err
+	return m.issueTokens(tokens.TokenInfo{UserID: tokenInfo.UserID, Role: role, SessionID: tokenInfo.SessionID}, accessTokenTTL, refreshTokenTTL)
+}
+
+// userRole returns the role to put into new tokens. Disabled users get no tokens.
+func (m *Manager) userRole(ctx context.Context, userID string) (string, error) {
+	role, disabled, err := m.db.GetUserAccess(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	if disabled {
+		return "", errs.Forbidden("account_disabled", "the account has been disabled")
+	}
+
+	return role, nil
 }
 
-func (m *Manager) RefreshTokens(refreshToken string, accessTokenTTL, refreshTokenTTL time.Duration) (string, string, error) {
-	tokenInfo, err := m.tokenManager.Parse(refreshToken)
+func (m *Manager) issueTokens(tokenInfo tokens.TokenInfo, accessTokenTTL, refreshTokenTTL time.Duration) (string, string, error) {
+	accessToken, err := m.tokenManager.NewJWT(tokenInfo, accessTokenTTL)
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err := m.tokenManager.NewJWT(tokenInfo, refreshTokenTTL)
 	if err != nil {
 		return "", "", err
 	}
 
-	return m.MakeTokens(tokenInfo.UserID, accessTokenTTL, refreshTokenTTL)
+	return accessToken, refreshToken, nil
 }
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"simple-finance/internal/cache"
+	"simple-finance/internal/db"
+	"simple-finance/internal/errs"
+	"simple-finance/internal/tokens"
+	"simple-finance/pkg/encrypt"
+	"simple-finance/pkg/totp"
+)
+
+const recoveryCodesCount = 10
+
+// MFAManager handles TOTP enrolment and the second sign-in step.
+// TOTP secrets are stored encrypted, recovery codes only as hashes.
+type MFAManager struct {
+	db           *db.FinanceDB
+	encryptor    encrypt.Encryptor
+	tokenManager *tokens.TokenManager
+	lockout      *cache.LoginLockout
+	issuer       string
+	challengeTTL time.Duration
+}
+
+func NewMFAManager(
+	db *db.FinanceDB,
+	encryptor encrypt.Encryptor,
+	tokenManager *tokens.TokenManager,
+	lockout *cache.LoginLockout,
+	issuer string,
+	challengeTTL time.Duration,
+) *MFAManager {
+	return &MFAManager{
+		db:           db,
+		encryptor:    encryptor,
+		tokenManager: tokenManager,
+		lockout:      lockout,
+		issuer:       issuer,
+		challengeTTL: challengeTTL,
+	}
+}
+
+// Enroll generates a new secret for the user. 2FA starts working only after
+// Confirm, until then the secret can be re-generated.
+func (m *MFAManager) Enroll(ctx context.Context, userID, accountName string) (string, string, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	encrypted, err := m.encryptor.Encrypt([]byte(secret))
+	if err != nil {
+		return "", "", err
+	}
+
+	err = m.db.SetPendingTOTP(ctx, userID, encrypted)
+	if err != nil {
+		return "", "", err
+	}
+
+	return secret, totp.URI(m.issuer, accountName, secret), nil
+}
+
+// Confirm enables 2FA once the user proves the authenticator app works and
+// returns one-time recovery codes. They are never shown again.
+func (m *MFAManager) Confirm(ctx context.Context, userID, code string) ([]string, error) {
+	secret, enabled, _, err := m.loadSecret(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, errs.Conflict("mfa_already_enabled", "two-factor authentication is already enabled")
+	}
+	if secret == "" {
+		return nil, errs.Conflict("mfa_not_pending", "two-factor authentication was not requested")
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return nil, errs.Validation("mfa_code_invalid", "invalid two-factor code")
+	}
+
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+	for i := 0; i < recoveryCodesCount; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hashToken(normalizeRecoveryCode(code)))
+	}
+
+	err = m.db.EnableTOTP(ctx, userID, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable turns 2FA off after checking a current TOTP code.
+func (m *MFAManager) Disable(ctx context.Context, userID, code string) error {
+	err := m.checkCode(ctx, userID, code, "")
+	if err != nil {
+		return err
+	}
+
+	return m.db.DisableTOTP(ctx, userID)
+}
+
+// Challenge returns a short-lived token for the second sign-in step if the
+// user has 2FA enabled, and an empty string otherwise.
+func (m *MFAManager) Challenge(ctx context.Context, userID string) (string, error) {
+	enabled, err := m.db.IsTOTPEnabled(ctx, userID)
+	if err != nil || !enabled {
+		return "", err
+	}
+
+	return m.tokenManager.NewPurposeJWT(tokens.PurposeMFAChallenge, userID, "", m.challengeTTL)
+}
+
+// VerifyChallenge checks the TOTP or recovery code for the challenge token and
+// returns the user ID. Failures count towards the sign-in lockout.
+func (m *MFAManager) VerifyChallenge(ctx context.Context, challenge, code, recoveryCode string) (string, error) {
+	userID, _, err := m.tokenManager.ParsePurpose(challenge, tokens.PurposeMFAChallenge)
+	if err != nil {
+		return "", errs.ErrInvalidToken
+	}
+
+	lockedFor, err := m.lockout.LockedFor(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if lockedFor > 0 {
+		return "", accountLocked(lockedFor)
+	}
+
+	err = m.checkCode(ctx, userID, code, recoveryCode)
+	if errs.IsKind(err, errs.ErrValidation) {
+		lockedFor, lockErr := m.lockout.Fail(ctx, userID)
+		if lockErr != nil {
+			return "", lockErr
+		}
+		if lockedFor > 0 {
+			return "", accountLocked(lockedFor)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	err = m.lockout.Reset(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	return userID, nil
+}
+
+func (m *MFAManager) checkCode(ctx context.Context, userID, code, recoveryCode string) error {
+	if recoveryCode != "" {
+		return m.db.UseRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(recoveryCode)))
+	}
+
+	secret, enabled, lastStep, err := m.loadSecret(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return errs.Conflict("mfa_not_enabled", "two-factor authentication is not enabled")
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok || step <= lastStep {
+		return errs.Validation("mfa_code_invalid", "invalid two-factor code")
+	}
+
+	return m.db.UseTOTPStep(ctx, userID, step)
+}
+
+func (m *MFAManager) loadSecret(ctx context.Context, userID string) (string, bool, int64, error) {
+	encrypted, enabled, lastStep, err := m.db.GetTOTP(ctx, userID)
+	if err != nil || encrypted == nil {
+		return "", enabled, lastStep, err
+	}
+
+	secret, err := m.encryptor.Decrypt(encrypted)
+	if err != nil {
+		return "", false, 0, err
+	}
+
+	return string(secret), enabled, lastStep, nil
+}
+
+// newRecoveryCode returns a code like "k7d2m-q9xfa".
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+
+	return code[:5] + "-" + code[5:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"simple-finance/internal/db"
+	"simple-finance/internal/errs"
+	"simple-finance/internal/mailer"
+	"simple-finance/pkg/hash"
+)
+
+// Очередь запросов сброса и время на обработку одного из них.
+const (
+	resetQueueSize = 100
+	resetTimeout   = 30 * time.Second
+)
+
+// PasswordResetter issues single-use reset tokens and sets new passwords.
+// Only the SHA-256 of a token is stored, the token itself exists only in the email.
+type PasswordResetter struct {
+	db          *db.FinanceDB
+	hasher      hash.PasswordHasher
+	authManager *Manager
+	mailer      mailer.Mailer
+	logger      *logrus.Logger
+	resetURL    string
+	ttl         time.Duration
+	requests    chan string
+	done        chan struct{}
+
+	// mu защищает closed: после Close отправка в requests вызвала бы панику.
+	mu     sync.Mutex
+	closed bool
+}
+
+func NewPasswordResetter(
+	db *db.FinanceDB,
+	hasher hash.PasswordHasher,
+	authManager *Manager,
+	mailer mailer.Mailer,
+	logger *logrus.Logger,
+	resetURL string,
+	ttl time.Duration,
+) *PasswordResetter {
+	p := &PasswordResetter{
+		db:          db,
+		hasher:      hasher,
+		authManager: authManager,
+		mailer:      mailer,
+		logger:      logger,
+		resetURL:    resetURL,
+		ttl:         ttl,
+		requests:    make(chan string, resetQueueSize),
+		done:        make(chan struct{}),
+	}
+
+	go p.work()
+
+	return p
+}
+
+// RequestReset queues a reset link for the email and returns at once. Lookup
+// and sending happen in the background and failures are only logged, so
+// neither the response nor its timing tells whether an account exists.
+func (p *PasswordResetter) RequestReset(email string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		p.logger.Warn("password reset is shutting down, request dropped")
+		return
+	}
+
+	select {
+	case p.requests <- email:
+	default:
+		p.logger.Warn("password reset queue is full, request dropped")
+	}
+}
+
+// Close stops accepting reset requests and waits for queued ones. The
+// database has to stay open until it returns.
+func (p *PasswordResetter) Close() error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.requests)
+	}
+	p.mu.Unlock()
+
+	<-p.done
+
+	return nil
+}
+
+func (p *PasswordResetter) work() {
+	defer close(p.done)
+
+	for email := range p.requests {
+		err := p.processReset(email)
+		if err != nil {
+			p.logger.Warn(err)
+		}
+	}
+}
+
+func (p *PasswordResetter) processReset(email string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), resetTimeout)
+	defer cancel()
+
+	userInfo, err := p.db.GetUserInfoByEmail(ctx, email)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return p.sendResetLink(ctx, userInfo.ID, userInfo.Email, userInfo.UserName)
+}
+
+// ForceReset is used by administrators: the current password stops working,
+// the user is signed out everywhere and gets a reset link.
+func (p *PasswordResetter) ForceReset(ctx context.Context, userID string) error {
+	userInfo, err := p.db.GetUserById(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// Случайный пароль никто не знает, войти можно только после сброса.
+	unusable, err := newSecretToken()
+	if err != nil {
+		return err
+	}
+
+	hashPass, err := p.hasher.Hash(unusable)
+	if err != nil {
+		return err
+	}
+
+	err = p.db.UpdatePassword(ctx, userID, hashPass)
+	if err != nil {
+		return err
+	}
+
+	err = p.authManager.RevokeTokens(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return p.sendResetLink(ctx, userInfo.ID, userInfo.Email, userInfo.UserName)
+}
+
+func (p *PasswordResetter) sendResetLink(ctx context.Context, userID, email, userName string) error {
+	token, err := newSecretToken()
+	if err != nil {
+		return err
+	}
+
+	err = p.db.InsertPasswordResetToken(ctx, userID, hashToken(token), time.Now().Add(p.ttl))
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s?token=%s", p.resetURL, url.QueryEscape(token))
+
+	return p.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf(
+			"Hello, %s!\n\nTo set a new password for Simple Finance open the link below:\n\n%s\n\nThe link can be used once and is valid for %s. If you did not request a reset, ignore this message.\n",
+			userName, link, p.ttl,
+		),
+	})
+}
+
+// Reset sets a new password and revokes all tokens of the user.
+func (p *PasswordResetter) Reset(ctx context.Context, token, newPass string) error {
+	newHashPass, err := p.hasher.Hash(newPass)
+	if err != nil {
+		return err
+	}
+
+	userID, err := p.db.ResetPassword(ctx, hashToken(token), newHashPass)
+	if err != nil {
+		return err
+	}
+
+	return p.authManager.RevokeTokens(ctx, userID)
+}
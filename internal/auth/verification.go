@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"simple-finance/internal/db"
+	"simple-finance/internal/errs"
+	"simple-finance/internal/mailer"
+	"simple-finance/internal/tokens"
+)
+
+// EmailVerifier sends signed verification links and confirms emails.
+type EmailVerifier struct {
+	db           *db.FinanceDB
+	tokenManager *tokens.TokenManager
+	mailer       mailer.Mailer
+	baseURL      string
+	ttl          time.Duration
+}
+
+func NewEmailVerifier(
+	db *db.FinanceDB,
+	tokenManager *tokens.TokenManager,
+	mailer mailer.Mailer,
+	baseURL string,
+	ttl time.Duration,
+) *EmailVerifier {
+	return &EmailVerifier{
+		db:           db,
+		tokenManager: tokenManager,
+		mailer:       mailer,
+		baseURL:      baseURL,
+		ttl:          ttl,
+	}
+}
+
+func (v *EmailVerifier) SendVerification(ctx context.Context, userID, email string) error {
+	token, err := v.tokenManager.NewPurposeJWT(tokens.PurposeEmailVerification, userID, email, v.ttl)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/auth/verify_email?token=%s", v.baseURL, url.QueryEscape(token))
+
+	return v.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf(
+			"Hello!\n\nTo confirm your email for Simple Finance open the link below:\n\n%s\n\nThe link is valid for %s. If you did not sign up, ignore this message.\n",
+			link, v.ttl,
+		),
+	})
+}
+
+// Verify confirms the email bound to the token and returns the user ID.
+func (v *EmailVerifier) Verify(ctx context.Context, token string) (string, error) {
+	userID, email, err := v.tokenManager.ParsePurpose(token, tokens.PurposeEmailVerification)
+	if err != nil {
+		return "", errs.Validation("verification_token_invalid", "verification token is invalid or expired")
+	}
+
+	err = v.db.VerifyEmail(ctx, userID, email)
+	if err != nil {
+		return "", err
+	}
+
+	return userID, nil
+}
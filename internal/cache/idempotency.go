@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const idempotencyPrefix = "idempotency:"
+
+// IdempotentResponse is a stored response to a request with an idempotency key.
+// Until the first request finishes it is pending and has no status. Responses
+// with secrets are not stored, only the fact that the request was completed.
+type IdempotentResponse struct {
+	Fingerprint string            `json:"fingerprint"`
+	Pending     bool              `json:"pending"`
+	NoStore     bool              `json:"no_store,omitempty"`
+	Status      int               `json:"status,omitempty"`
+	Header      map[string]string `json:"header,omitempty"`
+	Body        []byte            `json:"body,omitempty"`
+}
+
+// IdempotencyStore keeps responses to requests with idempotency keys, so a
+// retried request gets the original response instead of being executed again.
+type IdempotencyStore struct {
+	client *redis.Client
+}
+
+func NewIdempotencyStore(client *redis.Client) *IdempotencyStore {
+	return &IdempotencyStore{client: client}
+}
+
+// Begin reserves the key for a request with the given fingerprint. If the key
+// is already taken, the stored response is returned and started is false.
+// lockTTL limits how long a pending key lives if the request never finishes.
+func (s *IdempotencyStore) Begin(
+	ctx context.Context,
+	key, fingerprint string,
+	lockTTL time.Duration,
+) (stored IdempotentResponse, started bool, err error) {
+	pending, err := json.Marshal(IdempotentResponse{Fingerprint: fingerprint, Pending: true})
+	if err != nil {
+		return IdempotentResponse{}, false, err
+	}
+
+	started, err = s.client.SetNX(ctx, idempotencyPrefix+key, pending, lockTTL).Result()
+	if err != nil || started {
+		return IdempotentResponse{}, started, err
+	}
+
+	value, err := s.client.Get(ctx, idempotencyPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// Ключ истёк между SETNX и GET — пробуем занять его ещё раз.
+		return s.Begin(ctx, key, fingerprint, lockTTL)
+	}
+	if err != nil {
+		return IdempotentResponse{}, false, err
+	}
+
+	err = json.Unmarshal(value, &stored)
+	return stored, false, err
+}
+
+// Complete stores the response for replays during ttl.
+func (s *IdempotencyStore) Complete(ctx context.Context, key string, resp IdempotentResponse, ttl time.Duration) error {
+	value, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+
+	return s.client.Set(ctx, idempotencyPrefix+key, value, ttl).Err()
+}
+
+// Release frees the key, so the request can be retried with it.
+func (s *IdempotencyStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, idempotencyPrefix+key).Err()
+}
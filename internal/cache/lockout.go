@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	loginFailuresPrefix = "login_failures:"
+	loginLockPrefix     = "login_lock:"
+
+	// Сколько помним неудачные попытки входа.
+	loginFailuresTTL = 24 * time.Hour
+)
+
+// LoginLockout locks an account after repeated failed sign-ins. Every failure
+// past the threshold doubles the lock duration up to maxLock.
+type LoginLockout struct {
+	client    *redis.Client
+	threshold int
+	baseLock  time.Duration
+	maxLock   time.Duration
+}
+
+func NewLoginLockout(client *redis.Client, threshold int, baseLock, maxLock time.Duration) *LoginLockout {
+	return &LoginLockout{
+		client:    client,
+		threshold: threshold,
+		baseLock:  baseLock,
+		maxLock:   maxLock,
+	}
+}
+
+// LockedFor returns how long the account stays locked, zero if it is not.
+func (l *LoginLockout) LockedFor(ctx context.Context, userID string) (time.Duration, error) {
+	ttl, err := l.client.PTTL(ctx, loginLockPrefix+userID).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	// PTTL возвращает отрицательные значения, если ключа нет.
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+// Fail records a failed attempt and returns the lock duration it caused.
+func (l *LoginLockout) Fail(ctx context.Context, userID string) (time.Duration, error) {
+	key := loginFailuresPrefix + userID
+
+	pipe := l.client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, loginFailuresTTL)
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	failures := int(incr.Val())
+	if failures < l.threshold {
+		return 0, nil
+	}
+
+	lock := l.baseLock
+	for i := l.threshold; i < failures && lock < l.maxLock; i++ {
+		lock *= 2
+	}
+	lock = min(lock, l.maxLock)
+
+	err = l.client.Set(ctx, loginLockPrefix+userID, 1, lock).Err()
+	if err != nil {
+		return 0, err
+	}
+
+	return lock, nil
+}
+
+// Reset forgets failed attempts after a successful sign-in.
+func (l *LoginLockout) Reset(ctx context.Context, userID string) error {
+	return l.client.Del(ctx, loginFailuresPrefix+userID, loginLockPrefix+userID).Err()
+}
@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	profilePrefix = "profile:"
+	profileTTL    = 10 * time.Minute
+)
+
+// ProfileCache stores JSON-encoded user profiles in Redis. Every code path
+// that changes profile data must call Invalidate.
+type ProfileCache struct {
+	client *redis.Client
+}
+
+func NewProfileCache(client *redis.Client) *ProfileCache {
+	return &ProfileCache{client: client}
+}
+
+// Get returns the cached profile, ok is false on a cache miss.
+func (c *ProfileCache) Get(ctx context.Context, userID string) ([]byte, bool, error) {
+	data, err := c.client.Get(ctx, profilePrefix+userID).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return data, true, nil
+}
+
+func (c *ProfileCache) Set(ctx context.Context, userID string, data []byte) error {
+	return c.client.Set(ctx, profilePrefix+userID, data, profileTTL).Err()
+}
+
+func (c *ProfileCache) Invalidate(ctx context.Context, userID string) error {
+	return c.client.Del(ctx, profilePrefix+userID).Err()
+}
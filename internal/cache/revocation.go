@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const revokedPrefix = "tokens_revoked:"
+
+// TokenRevocations remembers the moment after which tokens of a user are
+// valid again. Tokens issued earlier are rejected.
+type TokenRevocations struct {
+	client *redis.Client
+}
+
+func NewTokenRevocations(client *redis.Client) *TokenRevocations {
+	return &TokenRevocations{client: client}
+}
+
+// RevokeBefore invalidates all tokens of the user issued before t. ttl must be
+// at least the lifetime of the longest token, after that the record is useless.
+func (r *TokenRevocations) RevokeBefore(ctx context.Context, userID string, t time.Time, ttl time.Duration) error {
+	return r.client.Set(ctx, revokedPrefix+userID, t.Unix(), ttl).Err()
+}
+
+// RevokedBefore returns the revocation moment, ok is false if there is none.
+func (r *TokenRevocations) RevokedBefore(ctx context.Context, userID string) (time.Time, bool, error) {
+	value, err := r.client.Get(ctx, revokedPrefix+userID).Result()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	unix, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	return time.Unix(unix, 0), true, nil
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+func durationEnv(key string, def time.Duration) (time.Duration, error) {
+	value, found := os.LookupEnv(key)
+	if !found || value == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return d, nil
+}
+
+func int64Env(key string, def int64) (int64, error) {
+	value, found := os.LookupEnv(key)
+	if !found || value == "" {
+		return def, nil
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return n, nil
+}
+
+func boolEnv(key string, def bool) (bool, error) {
+	value, found := os.LookupEnv(key)
+	if !found || value == "" {
+		return def, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return b, nil
+}
+
+func stringEnv(key string, def string) string {
+	value, found := os.LookupEnv(key)
+	if !found || value == "" {
+		return def
+	}
+
+	return value
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+type HTTPConfig interface {
+	Address() string
+	ReadTimeout() time.Duration
+	ReadHeaderTimeout() time.Duration
+	WriteTimeout() time.Duration
+	IdleTimeout() time.Duration
+	MaxBodyBytes() int64
+	MaxBatchBodyBytes() int64
+	APITimeout() time.Duration
+	AuthTimeout() time.Duration
+	IdempotencyTTL() time.Duration
+}
+
+type httpConfig struct {
+	address           string
+	readTimeout       time.Duration
+	readHeaderTimeout time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	maxBodyBytes      int64
+	maxBatchBodyBytes int64
+	apiTimeout        time.Duration
+	authTimeout       time.Duration
+	idempotencyTTL    time.Duration
+}
+
+func NewHTTPConfig() (HTTPConfig, error) {
+	serverPort, found := os.LookupEnv("SERVER_PORT")
+	if !found || serverPort == "" {
+		return nil, errors.New("SERVER_PORT not found")
+	}
+
+	cfg := &httpConfig{
+		address: fmt.Sprintf(":%s", serverPort),
+	}
+
+	var err error
+	if cfg.readTimeout, err = durationEnv("HTTP_READ_TIMEOUT", 10*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.readHeaderTimeout, err = durationEnv("HTTP_READ_HEADER_TIMEOUT", 5*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.writeTimeout, err = durationEnv("HTTP_WRITE_TIMEOUT", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.idleTimeout, err = durationEnv("HTTP_IDLE_TIMEOUT", 120*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.apiTimeout, err = durationEnv("HTTP_API_TIMEOUT", 15*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.authTimeout, err = durationEnv("HTTP_AUTH_TIMEOUT", 5*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.idempotencyTTL, err = durationEnv("IDEMPOTENCY_TTL", 24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.maxBodyBytes, err = int64Env("HTTP_MAX_BODY_BYTES", 1<<20); err != nil {
+		return nil, err
+	}
+	// 5000 операций с длинными комментариями на кириллице занимают около 11 МиБ.
+	if cfg.maxBatchBodyBytes, err = int64Env("HTTP_MAX_BATCH_BODY_BYTES", 16<<20); err != nil {
+		return nil, err
+	}
+
+	// Дедлайн запроса должен истекать раньше, чем сервер оборвёт запись ответа,
+	// иначе клиент не получит сообщение об ошибке.
+	if cfg.apiTimeout >= cfg.writeTimeout || cfg.authTimeout >= cfg.writeTimeout {
+		return nil, errors.New("HTTP_API_TIMEOUT and HTTP_AUTH_TIMEOUT must be less than HTTP_WRITE_TIMEOUT")
+	}
+
+	return cfg, nil
+}
+
+func (cfg *httpConfig) Address() string {
+	return cfg.address
+}
+
+func (cfg *httpConfig) ReadTimeout() time.Duration {
+	return cfg.readTimeout
+}
+
+func (cfg *httpConfig) ReadHeaderTimeout() time.Duration {
+	return cfg.readHeaderTimeout
+}
+
+func (cfg *httpConfig) WriteTimeout() time.Duration {
+	return cfg.writeTimeout
+}
+
+func (cfg *httpConfig) IdleTimeout() time.Duration {
+	return cfg.idleTimeout
+}
+
+func (cfg *httpConfig) MaxBodyBytes() int64 {
+	return cfg.maxBodyBytes
+}
+
+// MaxBatchBodyBytes is the body limit of bulk transaction creation, which
+// accepts up to 5000 transactions in one request.
+func (cfg *httpConfig) MaxBatchBodyBytes() int64 {
+	return cfg.maxBatchBodyBytes
+}
+
+// APITimeout is the deadline for requests to the /api routes.
+func (cfg *httpConfig) APITimeout() time.Duration {
+	return cfg.apiTimeout
+}
+
+// AuthTimeout is the deadline for requests to the /auth routes.
+func (cfg *httpConfig) AuthTimeout() time.Duration {
+	return cfg.authTimeout
+}
+
+// IdempotencyTTL is how long responses are kept for retries with the same
+// Idempotency-Key.
+func (cfg *httpConfig) IdempotencyTTL() time.Duration {
+	return cfg.idempotencyTTL
+}
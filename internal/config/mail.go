@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"net/mail"
+	"os"
+	"time"
+)
+
+// Способы отправки писем
+const (
+	MailDriverSMTP = "smtp"
+	MailDriverFile = "file"
+	MailDriverLog  = "log"
+)
+
+type MailConfig interface {
+	Driver() string
+	SMTPHost() string
+	SMTPPort() string
+	SMTPUser() string
+	SMTPPassword() string
+	From() *mail.Address
+	Dir() string
+	BaseURL() string
+	VerificationTTL() time.Duration
+	RequireVerifiedEmail() bool
+	PasswordResetURL() string
+	PasswordResetTTL() time.Duration
+}
+
+type mailConfig struct {
+	driver               string
+	smtpHost             string
+	smtpPort             string
+	smtpUser             string
+	smtpPassword         string
+	from                 *mail.Address
+	dir                  string
+	baseURL              string
+	verificationTTL      time.Duration
+	requireVerifiedEmail bool
+	passwordResetURL     string
+	passwordResetTTL     time.Duration
+}
+
+func NewMailConfig() (MailConfig, error) {
+	cfg := &mailConfig{
+		driver:       stringEnv("MAIL_DRIVER", MailDriverLog),
+		smtpHost:     os.Getenv("SMTP_HOST"),
+		smtpPort:     stringEnv("SMTP_PORT", "587"),
+		smtpUser:     os.Getenv("SMTP_USER"),
+		smtpPassword: os.Getenv("SMTP_PASSWORD"),
+		dir:          stringEnv("MAIL_DIR", "mail"),
+		baseURL:      stringEnv("APP_BASE_URL", "http://localhost:8000"),
+	}
+	cfg.passwordResetURL = stringEnv("PASSWORD_RESET_URL", cfg.baseURL+"/reset_password")
+
+	from, err := mail.ParseAddress(stringEnv("MAIL_FROM", "Simple Finance <no-reply@simple-finance.local>"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAIL_FROM: %w", err)
+	}
+	cfg.from = from
+
+	switch cfg.driver {
+	case MailDriverSMTP:
+		if cfg.smtpHost == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required for MAIL_DRIVER=%s", cfg.driver)
+		}
+	case MailDriverFile, MailDriverLog:
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER %q", cfg.driver)
+	}
+
+	if cfg.verificationTTL, err = durationEnv("EMAIL_VERIFICATION_TTL", 48*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.requireVerifiedEmail, err = boolEnv("REQUIRE_VERIFIED_EMAIL", false); err != nil {
+		return nil, err
+	}
+	if cfg.passwordResetTTL, err = durationEnv("PASSWORD_RESET_TTL", time.Hour); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func (cfg *mailConfig) Driver() string {
+	return cfg.driver
+}
+
+func (cfg *mailConfig) SMTPHost() string {
+	return cfg.smtpHost
+}
+
+func (cfg *mailConfig) SMTPPort() string {
+	return cfg.smtpPort
+}
+
+func (cfg *mailConfig) SMTPUser() string {
+	return cfg.smtpUser
+}
+
+func (cfg *mailConfig) SMTPPassword() string {
+	return cfg.smtpPassword
+}
+
+// From is the sender of all emails, used as is in the From header and by
+// address in the SMTP envelope.
+func (cfg *mailConfig) From() *mail.Address {
+	return cfg.from
+}
+
+// Dir is where the file driver stores messages.
+func (cfg *mailConfig) Dir() string {
+	return cfg.dir
+}
+
+// BaseURL is the public address of the API used in links sent by email.
+func (cfg *mailConfig) BaseURL() string {
+	return cfg.baseURL
+}
+
+func (cfg *mailConfig) VerificationTTL() time.Duration {
+	return cfg.verificationTTL
+}
+
+// RequireVerifiedEmail blocks users with unverified email from the /api routes.
+func (cfg *mailConfig) RequireVerifiedEmail() bool {
+	return cfg.requireVerifiedEmail
+}
+
+// PasswordResetURL is the page of the client app where the user enters a new
+// password. The reset token is appended as the token query parameter.
+func (cfg *mailConfig) PasswordResetURL() string {
+	return cfg.passwordResetURL
+}
+
+func (cfg *mailConfig) PasswordResetTTL() time.Duration {
+	return cfg.passwordResetTTL
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+type MFAConfig interface {
+	EncryptionKey() []byte
+	Issuer() string
+	ChallengeTTL() time.Duration
+}
+
+type mfaConfig struct {
+	encryptionKey []byte
+	issuer        string
+	challengeTTL  time.Duration
+}
+
+func NewMFAConfig() (MFAConfig, error) {
+	encodedKey, found := os.LookupEnv("MFA_ENCRYPTION_KEY")
+	if !found || encodedKey == "" {
+		return nil, errors.New("MFA_ENCRYPTION_KEY is not set, generate a key for this deployment with `openssl rand -base64 32`")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("MFA_ENCRYPTION_KEY: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, errors.New("MFA_ENCRYPTION_KEY must be 32 bytes encoded in base64")
+	}
+
+	cfg := &mfaConfig{
+		encryptionKey: key,
+		issuer:        stringEnv("MFA_ISSUER", "Simple Finance"),
+	}
+
+	if cfg.challengeTTL, err = durationEnv("MFA_CHALLENGE_TTL", 5*time.Minute); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// EncryptionKey encrypts TOTP secrets stored in the database.
+func (cfg *mfaConfig) EncryptionKey() []byte {
+	return cfg.encryptionKey
+}
+
+// Issuer is shown in authenticator apps next to the account name.
+func (cfg *mfaConfig) Issuer() string {
+	return cfg.issuer
+}
+
+// ChallengeTTL is how long the second sign-in step may take.
+func (cfg *mfaConfig) ChallengeTTL() time.Duration {
+	return cfg.challengeTTL
+}
@@ -8,10 +8,12 @@ import (
 
 type PGConfig interface {
 	DSN() string
+	AutoMigrate() bool
 }
 
 type pgConfig struct {
-	dsn string
+	dsn         string
+	autoMigrate bool
 }
 
 func NewPGConfig() (PGConfig, error) {
@@ -40,11 +42,22 @@ func NewPGConfig() (PGConfig, error) {
 		return nil, errors.New("DB_NAME not found")
 	}
 
+	// Применять миграции при старте приложения, по умолчанию выключено.
+	autoMigrate, err := boolEnv("DB_AUTO_MIGRATE", false)
+	if err != nil {
+		return nil, err
+	}
+
 	return &pgConfig{
-		dsn: fmt.Sprintf("postgres://%s:%s@%s:%s/%s", dbUser, dbPass, dbHost, dbPort, dbName),
+		dsn:         fmt.Sprintf("postgres://%s:%s@%s:%s/%s", dbUser, dbPass, dbHost, dbPort, dbName),
+		autoMigrate: autoMigrate,
 	}, nil
 }
 
 func (cfg *pgConfig) DSN() string {
 	return cfg.dsn
 }
+
+func (cfg *pgConfig) AutoMigrate() bool {
+	return cfg.autoMigrate
+}
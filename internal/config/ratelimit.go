@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Quota allows Limit requests per Window.
+type Quota struct {
+	Limit  int
+	Window time.Duration
+}
+
+type RateLimitConfig interface {
+	Enabled() bool
+	Auth() Quota
+	SignIn() Quota
+	API() Quota
+	LockoutThreshold() int
+	LockoutLoginThreshold() int
+	LockoutBase() time.Duration
+	LockoutMax() time.Duration
+}
+
+type rateLimitConfig struct {
+	enabled               bool
+	auth                  Quota
+	signIn                Quota
+	api                   Quota
+	lockoutThreshold      int
+	lockoutLoginThreshold int
+	lockoutBase           time.Duration
+	lockoutMax            time.Duration
+}
+
+func NewRateLimitConfig() (RateLimitConfig, error) {
+	cfg := &rateLimitConfig{}
+
+	var err error
+	if cfg.enabled, err = boolEnv("RATE_LIMIT_ENABLED", true); err != nil {
+		return nil, err
+	}
+	if cfg.auth, err = quotaEnv("RATE_LIMIT_AUTH", Quota{Limit: 30, Window: time.Minute}); err != nil {
+		return nil, err
+	}
+	if cfg.signIn, err = quotaEnv("RATE_LIMIT_SIGN_IN", Quota{Limit: 10, Window: time.Minute}); err != nil {
+		return nil, err
+	}
+	if cfg.api, err = quotaEnv("RATE_LIMIT_API", Quota{Limit: 600, Window: time.Minute}); err != nil {
+		return nil, err
+	}
+
+	threshold, err := int64Env("LOCKOUT_THRESHOLD", 5)
+	if err != nil {
+		return nil, err
+	}
+	cfg.lockoutThreshold = int(threshold)
+
+	loginThreshold, err := int64Env("LOCKOUT_LOGIN_THRESHOLD", 50)
+	if err != nil {
+		return nil, err
+	}
+	cfg.lockoutLoginThreshold = int(loginThreshold)
+
+	if cfg.lockoutBase, err = durationEnv("LOCKOUT_BASE", time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.lockoutMax, err = durationEnv("LOCKOUT_MAX", time.Hour); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func (cfg *rateLimitConfig) Enabled() bool {
+	return cfg.enabled
+}
+
+// Auth is the per-IP quota for all /auth routes.
+func (cfg *rateLimitConfig) Auth() Quota {
+	return cfg.auth
+}
+
+// SignIn is the additional per-IP quota for /auth/sign_in.
+func (cfg *rateLimitConfig) SignIn() Quota {
+	return cfg.signIn
+}
+
+// API is the per-IP and per-user quota for the /api routes.
+func (cfg *rateLimitConfig) API() Quota {
+	return cfg.api
+}
+
+// LockoutThreshold is the number of failed sign-ins from one IP address
+// before the login is locked for that address.
+func (cfg *rateLimitConfig) LockoutThreshold() int {
+	return cfg.lockoutThreshold
+}
+
+// LockoutLoginThreshold is the number of failed sign-ins from all addresses
+// before the login is locked for everyone. It is higher than LockoutThreshold,
+// so failures from other addresses do not easily lock out the owner.
+func (cfg *rateLimitConfig) LockoutLoginThreshold() int {
+	return cfg.lockoutLoginThreshold
+}
+
+func (cfg *rateLimitConfig) LockoutBase() time.Duration {
+	return cfg.lockoutBase
+}
+
+func (cfg *rateLimitConfig) LockoutMax() time.Duration {
+	return cfg.lockoutMax
+}
+
+// quotaEnv parses quotas like "100/1m".
+func quotaEnv(key string, def Quota) (Quota, error) {
+	value, found := os.LookupEnv(key)
+	if !found || value == "" {
+		return def, nil
+	}
+
+	limitStr, windowStr, ok := strings.Cut(value, "/")
+	if !ok {
+		return Quota{}, fmt.Errorf("%s: expected format <limit>/<window>, e.g. 100/1m", key)
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		return Quota{}, fmt.Errorf("%s: invalid limit %q", key, limitStr)
+	}
+
+	window, err := time.ParseDuration(windowStr)
+	if err != nil || window <= 0 {
+		return Quota{}, fmt.Errorf("%s: invalid window %q", key, windowStr)
+	}
+
+	return Quota{Limit: limit, Window: window}, nil
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+type TrashConfig interface {
+	Retention() time.Duration
+	PurgeInterval() time.Duration
+}
+
+type trashConfig struct {
+	retention     time.Duration
+	purgeInterval time.Duration
+}
+
+func NewTrashConfig() (TrashConfig, error) {
+	cfg := &trashConfig{}
+
+	var err error
+	if cfg.retention, err = durationEnv("TRASH_RETENTION", 30*24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.purgeInterval, err = durationEnv("TRASH_PURGE_INTERVAL", time.Hour); err != nil {
+		return nil, err
+	}
+
+	if cfg.retention <= 0 {
+		return nil, errors.New("TRASH_RETENTION must be positive")
+	}
+	if cfg.purgeInterval <= 0 {
+		return nil, errors.New("TRASH_PURGE_INTERVAL must be positive")
+	}
+
+	return cfg, nil
+}
+
+// Retention is how long deleted data can be restored before it is purged.
+func (cfg *trashConfig) Retention() time.Duration {
+	return cfg.retention
+}
+
+// PurgeInterval is how often expired data is removed from the trash.
+func (cfg *trashConfig) PurgeInterval() time.Duration {
+	return cfg.purgeInterval
+}
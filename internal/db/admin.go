@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"simple-finance/internal/errs"
+	"simple-finance/internal/models"
+)
+
+// GetUserAccess returns the role of the user and whether the account is disabled.
+func (db *FinanceDB) GetUserAccess(ctx context.Context, userID string) (string, bool, error) {
+	const query = `SELECT role, disabled_at IS NOT NULL FROM users WHERE id = $1`
+
+	var (
+		role     string
+		disabled bool
+	)
+	err := db.conn.QueryRow(ctx, query, userID).Scan(&role, &disabled)
+
+	return role, disabled, mapError(err, "user")
+}
+
+// SearchUsers returns a page of users whose username or email contains the
+// query, newest first, and the total number of matches.
+func (db *FinanceDB) SearchUsers(ctx context.Context, filter models.UserFilter) (models.UserList, error) {
+	const query = `
+		SELECT id, email, email_verified_at IS NOT NULL, username, role,
+		       totp_enabled_at IS NOT NULL, disabled_at, created_at,
+		       COUNT(*) OVER ()
+		FROM users
+		WHERE $1 = '' OR username ILIKE $2 OR email ILIKE $2
+		ORDER BY created_at DESC, id
+		LIMIT $3 OFFSET $4
+	`
+
+	pattern := "%" + escapeLike(filter.Query) + "%"
+
+	rows, err := db.conn.Query(ctx, query, filter.Query, pattern, filter.Limit, filter.Offset)
+	if err != nil {
+		return models.UserList{}, err
+	}
+	defer rows.Close()
+
+	list := models.UserList{Users: make([]models.AdminUser, 0)}
+
+	for rows.Next() {
+		var user models.AdminUser
+
+		err := rows.Scan(
+			&user.ID,
+			&user.Email,
+			&user.EmailVerified,
+			&user.UserName,
+			&user.Role,
+			&user.TOTPEnabled,
+			&user.DisabledAt,
+			&user.CreatedAt,
+			&list.Total,
+		)
+		if err != nil {
+			return models.UserList{}, err
+		}
+
+		list.Users = append(list.Users, user)
+	}
+
+	return list, rows.Err()
+}
+
+// SetUserDisabled disables or re-enables the account.
+func (db *FinanceDB) SetUserDisabled(ctx context.Context, userID string, disabled bool) error {
+	const query = `
+		UPDATE users
+		SET disabled_at = CASE WHEN $2 THEN COALESCE(disabled_at, NOW()) END
+		WHERE id = $1
+	`
+
+	return pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, query, userID, disabled)
+		if err != nil {
+			return mapError(err, "user")
+		}
+
+		if tag.RowsAffected() == 0 {
+			return errs.NotFound("user_not_found", "user not found")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "user",
+			entityID: userID,
+			action:   models.AuditUpdate,
+			userID:   userID,
+			after:    map[string]bool{"disabled": disabled},
+		})
+	})
+}
+
+func (db *FinanceDB) SetUserRole(ctx context.Context, userID, role string) error {
+	return pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		before, err := getUserForUpdate(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE users SET role = $2, version = version + 1 WHERE id = $1`, userID, role)
+		if err != nil {
+			return mapError(err, "user")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "user",
+			entityID: userID,
+			action:   models.AuditUpdate,
+			userID:   userID,
+			before:   map[string]string{"role": before.Role},
+			after:    map[string]string{"role": role},
+		})
+	})
+}
+
+func (db *FinanceDB) GetSystemStats(ctx context.Context) (models.SystemStats, error) {
+	const query = `
+		SELECT
+			(SELECT COUNT(*) FROM users),
+			(SELECT COUNT(*) FROM users WHERE email_verified_at IS NOT NULL),
+			(SELECT COUNT(*) FROM users WHERE disabled_at IS NOT NULL),
+			(SELECT COUNT(*) FROM users WHERE role = 'admin'),
+			(SELECT COUNT(*) FROM users WHERE totp_enabled_at IS NOT NULL),
+			(SELECT COUNT(*) FROM transactions WHERE deleted_at IS NULL),
+			(SELECT COUNT(*) FROM sessions WHERE revoked_at IS NULL AND expires_at > NOW()),
+			(SELECT COUNT(*) FROM api_tokens WHERE expires_at IS NULL OR expires_at > NOW())
+	`
+
+	var stats models.SystemStats
+	err := db.conn.QueryRow(ctx, query).Scan(
+		&stats.Users,
+		&stats.VerifiedUsers,
+		&stats.DisabledUsers,
+		&stats.Admins,
+		&stats.TOTPUsers,
+		&stats.Transactions,
+		&stats.ActiveSessions,
+		&stats.APITokens,
+	)
+
+	return stats, err
+}
+
+// escapeLike экранирует спецсимволы LIKE, чтобы поиск шёл по подстроке как есть.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"simple-finance/internal/models"
+	"simple-finance/internal/tokens"
+)
+
+// lastUsedPrecision ограничивает частоту записи last_used_at и last_seen_at:
+// клиенты делают много запросов подряд, и обновлять строку на каждый незачем.
+const lastUsedPrecision = time.Minute
+
+func (db *FinanceDB) InsertAPIToken(ctx context.Context, userID, tokenHash string, token models.APIToken) (models.APIToken, error) {
+	const query = `
+		INSERT INTO api_tokens (id, user_id, name, token_hash, scopes, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		RETURNING created_at
+	`
+
+	err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, query, token.ID, userID, token.Name, tokenHash, token.Scopes, token.ExpiresAt).
+			Scan(&token.CreatedAt)
+		if err != nil {
+			return mapError(err, "api_token")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "api_token",
+			entityID: token.ID,
+			action:   models.AuditCreate,
+			userID:   userID,
+			after:    token,
+		})
+	})
+
+	return token, err
+}
+
+func (db *FinanceDB) GetAPITokens(ctx context.Context, userID string) ([]models.APIToken, error) {
+	const query = `
+		SELECT id, name, scopes, expires_at, last_used_at, created_at
+		FROM api_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := db.conn.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	apiTokens := make([]models.APIToken, 0)
+
+	for rows.Next() {
+		var token models.APIToken
+
+		err := rows.Scan(
+			&token.ID,
+			&token.Name,
+			&token.Scopes,
+			&token.ExpiresAt,
+			&token.LastUsedAt,
+			&token.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		apiTokens = append(apiTokens, token)
+	}
+
+	return apiTokens, rows.Err()
+}
+
+func (db *FinanceDB) DeleteAPIToken(ctx context.Context, userID, tokenID string) error {
+	const query = `
+		DELETE FROM api_tokens
+		WHERE user_id = $1 AND id = $2
+		RETURNING id, name, scopes, expires_at, last_used_at, created_at
+	`
+
+	return pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		var token models.APIToken
+		err := tx.QueryRow(ctx, query, userID, tokenID).Scan(
+			&token.ID,
+			&token.Name,
+			&token.Scopes,
+			&token.ExpiresAt,
+			&token.LastUsedAt,
+			&token.CreatedAt,
+		)
+		if err != nil {
+			return mapError(err, "api_token")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "api_token",
+			entityID: token.ID,
+			action:   models.AuditDelete,
+			userID:   userID,
+			before:   token,
+		})
+	})
+}
+
+// UseAPIToken finds an unexpired token of an active user by its hash and
+// records that it was used.
+func (db *FinanceDB) UseAPIToken(ctx context.Context, tokenHash string) (tokens.TokenInfo, error) {
+	const query = `
+		SELECT t.id, t.user_id, u.role, t.scopes, t.last_used_at
+		FROM api_tokens t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.token_hash = $1
+		  AND (t.expires_at IS NULL OR t.expires_at > NOW())
+		  AND u.disabled_at IS NULL
+	`
+	const touch = "UPDATE api_tokens SET last_used_at = NOW() WHERE id = $1"
+
+	var (
+		tokenInfo  tokens.TokenInfo
+		lastUsedAt *time.Time
+	)
+	err := db.conn.QueryRow(ctx, query, tokenHash).Scan(
+		&tokenInfo.APITokenID,
+		&tokenInfo.UserID,
+		&tokenInfo.Role,
+		&tokenInfo.Scopes,
+		&lastUsedAt,
+	)
+	if err != nil {
+		return tokens.TokenInfo{}, mapError(err, "api_token")
+	}
+
+	if lastUsedAt == nil || time.Since(*lastUsedAt) > lastUsedPrecision {
+		_, err = db.conn.Exec(ctx, touch, tokenInfo.APITokenID)
+		if err != nil {
+			return tokens.TokenInfo{}, err
+		}
+	}
+
+	return tokenInfo, nil
+}
@@ -0,0 +1,166 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5"
+	"simple-finance/internal/models"
+)
+
+type auditMetaKey struct{}
+
+// AuditMeta describes who makes the change and from where. It travels in the
+// request context, so FinanceDB methods keep their signatures.
+type AuditMeta struct {
+	ActorID   string
+	RequestID string
+	IP        string
+}
+
+func WithAuditMeta(ctx context.Context, meta AuditMeta) context.Context {
+	return context.WithValue(ctx, auditMetaKey{}, meta)
+}
+
+func auditMetaFromContext(ctx context.Context) AuditMeta {
+	meta, _ := ctx.Value(auditMetaKey{}).(AuditMeta)
+	return meta
+}
+
+// auditRecord describes a change. userID is set for personal data such as the
+// profile, ledgerID for data of a ledger, so both can be found in the log later.
+type auditRecord struct {
+	entity   string
+	entityID string
+	action   string
+	userID   string
+	ledgerID string
+	before   any
+	after    any
+}
+
+const insertAuditQuery = `
+	INSERT INTO audit_log (actor_id, user_id, ledger_id, entity, entity_id, action, before, after, request_id, ip, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW())
+`
+
+// writeAudit appends the record to the audit log. It must be called with the
+// transaction that makes the change, so the log never disagrees with the data.
+func writeAudit(ctx context.Context, q querier, record auditRecord) error {
+	args, err := auditArgs(ctx, record)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec(ctx, insertAuditQuery, args...)
+
+	return err
+}
+
+// queueAudit adds the record to a batch, so changes of many rows are logged
+// in one round trip. The batch has to be sent with the changing transaction.
+func queueAudit(ctx context.Context, batch *pgx.Batch, record auditRecord) error {
+	args, err := auditArgs(ctx, record)
+	if err != nil {
+		return err
+	}
+
+	batch.Queue(insertAuditQuery, args...)
+
+	return nil
+}
+
+func auditArgs(ctx context.Context, record auditRecord) ([]any, error) {
+	meta := auditMetaFromContext(ctx)
+
+	before, err := auditSnapshot(record.before)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := auditSnapshot(record.after)
+	if err != nil {
+		return nil, err
+	}
+
+	return []any{
+		nullable(meta.ActorID),
+		nullable(record.userID),
+		nullable(record.ledgerID),
+		record.entity,
+		record.entityID,
+		record.action,
+		before,
+		after,
+		meta.RequestID,
+		meta.IP,
+	}, nil
+}
+
+func auditSnapshot(v any) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(v)
+}
+
+func nullable(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// GetAuditLog returns entries about the user's own data: changes the user made,
+// changes of their profile and settings and changes in ledgers they belong to.
+func (db *FinanceDB) GetAuditLog(ctx context.Context, userID string, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	const query = `
+		SELECT id, actor_id, user_id, ledger_id, entity, entity_id, action, before, after, request_id, ip, created_at
+		FROM audit_log
+		WHERE (
+			actor_id = $1
+			OR user_id = $1
+			OR ledger_id IN (SELECT ledger_id FROM ledger_members WHERE user_id = $1)
+		)
+		  AND ($2 = '' OR entity = $2)
+		  AND ($3 = '' OR entity_id = $3)
+		  AND ($4 = 0 OR id < $4)
+		ORDER BY id DESC
+		LIMIT $5
+	`
+
+	rows, err := db.conn.Query(ctx, query, userID, filter.Entity, filter.EntityID, filter.BeforeID, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]models.AuditEntry, 0)
+
+	for rows.Next() {
+		var entry models.AuditEntry
+
+		err := rows.Scan(
+			&entry.ID,
+			&entry.ActorID,
+			&entry.UserID,
+			&entry.LedgerID,
+			&entry.Entity,
+			&entry.EntityID,
+			&entry.Action,
+			&entry.Before,
+			&entry.After,
+			&entry.RequestID,
+			&entry.IP,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
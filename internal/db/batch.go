@@ -0,0 +1,604 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"simple-finance/internal/errs"
+	"simple-finance/internal/models"
+	"simple-finance/internal/rules"
+	"simple-finance/internal/suggest"
+)
+
+// maxBatchSize ограничивает число операций, которые выбирает фильтр.
+const maxBatchSize = 5000
+
+// InsertTransactions creates the transactions with COPY in one database
+// transaction, so either all of them are created or none. Authors have to be
+// owners or editors of the ledgers and categories must belong to them. Rules
+// are applied as in InsertTransaction, a category neither the client nor
+// rules gave is suggested from the author's history when the model is sure.
+func (db *FinanceDB) InsertTransactions(ctx context.Context, transactions []models.Transaction) ([]string, error) {
+	columns := []string{"id", "ledger_id", "user_id", "amount", "category_id", "comment", "date", "created_at"}
+
+	ids := make([]string, 0, len(transactions))
+
+	err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		engines := make(map[string]*rules.Engine)
+		suggesters := make(map[string]*suggest.Model)
+		categoryIDs := make([]string, 0, len(transactions))
+		var tagTransactionIDs, tagIDs []string
+
+		for i := range transactions {
+			transaction := &transactions[i]
+
+			engine, ok := engines[transaction.LedgerID]
+			if !ok {
+				err := requireLedgerRole(ctx, tx, transaction.LedgerID, transaction.UserID, ledgerWriters...)
+				if err != nil {
+					return err
+				}
+
+				engine, err = ledgerEngine(ctx, tx, transaction.LedgerID)
+				if err != nil {
+					return err
+				}
+				engines[transaction.LedgerID] = engine
+			}
+
+			for _, tagID := range assignByRules(engine, transaction) {
+				tagTransactionIDs = append(tagTransactionIDs, transaction.ID)
+				tagIDs = append(tagIDs, tagID)
+			}
+
+			// Если правила не помогли, категорию подсказывает история автора.
+			if transaction.CategoryID == "" {
+				model, ok := suggesters[transaction.LedgerID]
+				if !ok {
+					var err error
+					model, _, err = trainSuggester(ctx, tx, transaction.UserID, transaction.LedgerID)
+					if err != nil {
+						return err
+					}
+					suggesters[transaction.LedgerID] = model
+				}
+
+				if categoryID, ok := suggestForImport(model, *transaction); ok {
+					transaction.CategoryID = categoryID
+				}
+			}
+
+			if transaction.CategoryID != "" {
+				categoryIDs = append(categoryIDs, transaction.CategoryID)
+			}
+		}
+
+		categories, err := categoryLedgers(ctx, tx, categoryIDs)
+		if err != nil {
+			return err
+		}
+
+		// Сообщаем обо всех неверных категориях сразу, а не только о первой.
+		var fields []errs.FieldError
+		for i, transaction := range transactions {
+			if transaction.CategoryID == "" {
+				fields = append(fields, errs.FieldError{
+					Field:   fmt.Sprintf("transactions[%d].category_id", i),
+					Rule:    "required",
+					Message: "category_id is required when no rule assigns a category",
+				})
+				continue
+			}
+			if !strings.EqualFold(categories[strings.ToLower(transaction.CategoryID)], transaction.LedgerID) {
+				fields = append(fields, errs.FieldError{
+					Field:   fmt.Sprintf("transactions[%d].category_id", i),
+					Rule:    "category",
+					Message: "category does not exist",
+				})
+			}
+		}
+		if len(fields) > 0 {
+			return errs.InvalidFields("request contains invalid fields", fields)
+		}
+
+		var now time.Time
+		err = tx.QueryRow(ctx, `SELECT NOW()::timestamp(0)`).Scan(&now)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]any, 0, len(transactions))
+		batch := &pgx.Batch{}
+
+		for _, transaction := range transactions {
+			row, err := copyRow(transaction, now)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, row)
+
+			transaction.CreatedAt = now
+			transaction.Version = 1
+			transaction.DeletedAt = nil
+
+			err = queueAudit(ctx, batch, auditRecord{
+				entity:   "transaction",
+				entityID: transaction.ID,
+				action:   models.AuditCreate,
+				ledgerID: transaction.LedgerID,
+				after:    transaction,
+			})
+			if err != nil {
+				return err
+			}
+
+			ids = append(ids, transaction.ID)
+		}
+
+		_, err = tx.CopyFrom(ctx, pgx.Identifier{"transactions"}, columns, pgx.CopyFromRows(rows))
+		if err != nil {
+			return mapError(err, "transaction")
+		}
+
+		_, err = attachRuleTags(ctx, tx, tagTransactionIDs, tagIDs)
+		if err != nil {
+			return err
+		}
+
+		return tx.SendBatch(ctx, batch).Close()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// copyRow converts the transaction into COPY values. COPY uses the binary
+// format, so identifiers have to be real UUIDs rather than strings.
+func copyRow(transaction models.Transaction, createdAt time.Time) ([]any, error) {
+	ids := make([]pgtype.UUID, 0, 4)
+
+	for _, s := range []string{transaction.ID, transaction.LedgerID, transaction.UserID, transaction.CategoryID} {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil, errs.Validation("transaction_invalid_id", "invalid identifier format")
+		}
+		ids = append(ids, pgtype.UUID{Bytes: id, Valid: true})
+	}
+
+	return []any{
+		ids[0],
+		ids[1],
+		ids[2],
+		transaction.Amount,
+		ids[3],
+		transaction.Comment,
+		transaction.Date,
+		createdAt,
+	}, nil
+}
+
+// categoryLedgers returns ledgers of live categories by category ID.
+func categoryLedgers(ctx context.Context, tx pgx.Tx, categoryIDs []string) (map[string]string, error) {
+	const query = `
+		SELECT id, ledger_id FROM categories
+		WHERE id = ANY($1::text[]::uuid[]) AND deleted_at IS NULL
+	`
+
+	rows, err := tx.Query(ctx, query, uniqueIDs(categoryIDs))
+	if err != nil {
+		return nil, mapError(err, "category")
+	}
+	defer rows.Close()
+
+	ledgers := make(map[string]string)
+
+	for rows.Next() {
+		var categoryID, ledgerID string
+
+		err := rows.Scan(&categoryID, &ledgerID)
+		if err != nil {
+			return nil, err
+		}
+
+		ledgers[categoryID] = ledgerID
+	}
+
+	return ledgers, rows.Err()
+}
+
+// UpdateTransactions changes the category and the user's tags of many
+// transactions. Transactions the user may not change are reported as failed
+// items and skipped, the rest are changed in one database transaction.
+func (db *FinanceDB) UpdateTransactions(ctx context.Context, userID string, input models.BatchUpdateInput) (models.BatchResult, error) {
+	const categoryQuery = `SELECT ledger_id FROM categories WHERE id = $1 AND deleted_at IS NULL`
+	// Чужие теги на общих операциях не трогаем.
+	const detachTags = `
+		DELETE FROM transaction_tags tt
+		USING tags g
+		WHERE tt.tag_id = g.id AND g.user_id = $2 AND tt.transaction_id = ANY($1::text[]::uuid[])
+	`
+	const attachTags = `
+		INSERT INTO transaction_tags (transaction_id, tag_id)
+		SELECT t, g FROM unnest($1::text[]::uuid[]) t CROSS JOIN unnest($2::text[]::uuid[]) g
+	`
+	const query = `
+		UPDATE transactions AS t
+		SET category_id = COALESCE($2::uuid, category_id), version = version + 1
+		WHERE t.id = ANY($1::text[]::uuid[])
+		RETURNING` + transactionColumns
+
+	var result models.BatchResult
+
+	err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		ids, versions, err := selectBatch(ctx, tx, userID, input.IDs, input.Filter)
+		if err != nil {
+			return err
+		}
+
+		tagIDs := uniqueIDs(input.TagIDs)
+		err = requireOwnTags(ctx, tx, userID, tagIDs)
+		if err != nil {
+			return err
+		}
+
+		var check func(models.Transaction) error
+		if input.CategoryID != nil {
+			var categoryLedger string
+			err = tx.QueryRow(ctx, categoryQuery, *input.CategoryID).Scan(&categoryLedger)
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				return mapError(err, "category")
+			}
+
+			// Категория должна быть из той же книги, что и операция.
+			check = func(transaction models.Transaction) error {
+				if categoryLedger != transaction.LedgerID {
+					return errs.Validation("category_not_found", "category does not exist")
+				}
+				return nil
+			}
+		}
+
+		var allowed []models.Transaction
+		allowed, result, err = planBatch(ctx, tx, userID, ids, versions, check)
+		if err != nil || len(allowed) == 0 {
+			return err
+		}
+
+		allowedIDs := transactionIDs(allowed)
+
+		if input.TagIDs != nil {
+			_, err = tx.Exec(ctx, detachTags, allowedIDs, userID)
+			if err != nil {
+				return mapError(err, "tag")
+			}
+
+			if len(tagIDs) > 0 {
+				_, err = tx.Exec(ctx, attachTags, allowedIDs, tagIDs)
+				if err != nil {
+					return mapError(err, "tag")
+				}
+			}
+		}
+
+		return applyBatch(ctx, tx, models.AuditUpdate, allowed, query, allowedIDs, input.CategoryID)
+	})
+
+	return result, err
+}
+
+// DeleteTransactions moves many transactions to the trash. Transactions the
+// user may not delete are reported as failed items and skipped.
+func (db *FinanceDB) DeleteTransactions(ctx context.Context, userID string, input models.BatchDeleteInput) (models.BatchResult, error) {
+	const query = `
+		UPDATE transactions AS t
+		SET deleted_at = NOW(), version = version + 1
+		WHERE t.id = ANY($1::text[]::uuid[])
+		RETURNING` + transactionColumns
+
+	var result models.BatchResult
+
+	err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		ids, versions, err := selectBatch(ctx, tx, userID, input.IDs, input.Filter)
+		if err != nil {
+			return err
+		}
+
+		var allowed []models.Transaction
+		allowed, result, err = planBatch(ctx, tx, userID, ids, versions, nil)
+		if err != nil || len(allowed) == 0 {
+			return err
+		}
+
+		return applyBatch(ctx, tx, models.AuditDelete, allowed, query, transactionIDs(allowed))
+	})
+
+	return result, err
+}
+
+// requireOwnTags checks that all tags are live tags of the user. Tags are
+// personal, so nobody can attach tags of another ledger member.
+func requireOwnTags(ctx context.Context, tx pgx.Tx, userID string, tagIDs []string) error {
+	const query = `
+		SELECT COUNT(*) FROM tags
+		WHERE id = ANY($1::text[]::uuid[]) AND user_id = $2 AND deleted_at IS NULL
+	`
+
+	if len(tagIDs) == 0 {
+		return nil
+	}
+
+	var count int
+	err := tx.QueryRow(ctx, query, tagIDs, userID).Scan(&count)
+	if err != nil {
+		return mapError(err, "tag")
+	}
+
+	if count != len(tagIDs) {
+		return errs.Validation("tag_not_found", "tag does not exist")
+	}
+
+	return nil
+}
+
+// selectBatch returns IDs of the transactions a bulk operation works with and
+// the versions the client expects them at. A filter selects live transactions
+// of one ledger as they are now, so the user needs the writer role there and
+// no versions are returned. Targets are checked one by one in planBatch.
+func selectBatch(
+	ctx context.Context,
+	tx pgx.Tx,
+	userID string,
+	targets []models.BatchTarget,
+	filter *models.TransactionFilter,
+) ([]string, map[string]int64, error) {
+	const query = `
+		SELECT id FROM transactions
+		WHERE ledger_id = $1 AND deleted_at IS NULL
+		  AND ($2::uuid IS NULL OR category_id = $2::uuid)
+		  AND ($3::date IS NULL OR date >= $3::date)
+		  AND ($4::date IS NULL OR date <= $4::date)
+		ORDER BY date, id
+		LIMIT $5
+	`
+
+	if filter == nil {
+		ids, versions := batchTargets(targets)
+		return ids, versions, nil
+	}
+
+	err := requireLedgerRole(ctx, tx, filter.LedgerID, userID, ledgerWriters...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := tx.Query(ctx, query, filter.LedgerID, nullable(filter.CategoryID), filter.DateFrom, filter.DateTo, maxBatchSize+1)
+	if err != nil {
+		return nil, nil, mapError(err, "transaction")
+	}
+	defer rows.Close()
+
+	selected := make([]string, 0)
+
+	for rows.Next() {
+		var id string
+
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		selected = append(selected, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(selected) > maxBatchSize {
+		return nil, nil, errs.Validation("batch_too_large",
+			fmt.Sprintf("filter matches more than %d transactions, narrow it down", maxBatchSize))
+	}
+
+	return selected, nil, nil
+}
+
+// batchTargets splits targets into unique IDs and expected versions. For a
+// repeated ID the first version wins.
+func batchTargets(targets []models.BatchTarget) ([]string, map[string]int64) {
+	ids := make([]string, 0, len(targets))
+	versions := make(map[string]int64, len(targets))
+
+	for _, target := range targets {
+		id := strings.ToLower(target.ID)
+		if _, seen := versions[id]; seen {
+			continue
+		}
+		versions[id] = target.Version
+		ids = append(ids, id)
+	}
+
+	return ids, versions
+}
+
+// planBatch locks the transactions and decides for each one whether the user
+// may change it and whether it is still at the expected version. versions is
+// nil for filter selections. check adds operation-specific conditions and may be nil.
+func planBatch(
+	ctx context.Context,
+	tx pgx.Tx,
+	userID string,
+	ids []string,
+	versions map[string]int64,
+	check func(models.Transaction) error,
+) ([]models.Transaction, models.BatchResult, error) {
+	result := models.BatchResult{Items: make([]models.BatchItemResult, 0, len(ids))}
+
+	locked, err := lockTransactions(ctx, tx, userID, ids)
+	if err != nil {
+		return nil, result, err
+	}
+
+	roles := make(map[string]error)
+	allowed := make([]models.Transaction, 0, len(locked))
+
+	for _, id := range ids {
+		transaction, ok := locked[id]
+
+		var itemErr error
+		if !ok {
+			itemErr = errs.NotFound("transaction_not_found", "transaction not found")
+		} else {
+			roleErr, checked := roles[transaction.LedgerID]
+			if !checked {
+				roleErr = requireLedgerRole(ctx, tx, transaction.LedgerID, userID, ledgerWriters...)
+				roles[transaction.LedgerID] = roleErr
+			}
+
+			itemErr = roleErr
+			if expected, ok := versions[id]; ok && itemErr == nil {
+				itemErr = checkVersion(expected, transaction.Version)
+			}
+			if itemErr == nil && check != nil {
+				itemErr = check(transaction)
+			}
+		}
+
+		if itemErr == nil {
+			allowed = append(allowed, transaction)
+			result.Succeeded++
+			result.Items = append(result.Items, models.BatchItemResult{ID: id, Status: models.BatchItemOK})
+			continue
+		}
+
+		// Ошибки базы прерывают весь запрос, в отчёт попадают только доменные.
+		var domainErr *errs.Error
+		if !errors.As(itemErr, &domainErr) {
+			return nil, result, itemErr
+		}
+
+		result.Failed++
+		result.Items = append(result.Items, models.BatchItemResult{
+			ID:      id,
+			Status:  models.BatchItemFailed,
+			Code:    domainErr.Code,
+			Message: domainErr.Message,
+		})
+	}
+
+	return allowed, result, nil
+}
+
+// lockTransactions reads live transactions of the user's ledgers for a change.
+// Rows are locked in ID order, so concurrent bulk operations cannot deadlock.
+func lockTransactions(ctx context.Context, tx pgx.Tx, userID string, ids []string) (map[string]models.Transaction, error) {
+	const query = "SELECT" + transactionColumns + `
+		FROM transactions t
+		JOIN ledger_members m ON m.ledger_id = t.ledger_id AND m.user_id = $1
+		WHERE t.id = ANY($2::text[]::uuid[]) AND t.deleted_at IS NULL
+		ORDER BY t.id
+		FOR UPDATE OF t
+	`
+
+	rows, err := tx.Query(ctx, query, userID, ids)
+	if err != nil {
+		return nil, mapError(err, "transaction")
+	}
+	defer rows.Close()
+
+	locked := make(map[string]models.Transaction, len(ids))
+
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		locked[transaction.ID] = transaction
+	}
+
+	return locked, rows.Err()
+}
+
+// applyBatch runs the changing query for the allowed transactions and logs
+// every change. The query gets the IDs as $1 followed by args.
+func applyBatch(ctx context.Context, tx pgx.Tx, action string, allowed []models.Transaction, query string, args ...any) error {
+	changed, err := queryTransactions(ctx, tx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	batch := &pgx.Batch{}
+
+	for _, before := range allowed {
+		err := queueAudit(ctx, batch, auditRecord{
+			entity:   "transaction",
+			entityID: before.ID,
+			action:   action,
+			ledgerID: before.LedgerID,
+			before:   before,
+			after:    changed[before.ID],
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+func queryTransactions(ctx context.Context, tx pgx.Tx, query string, args ...any) (map[string]models.Transaction, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, mapError(err, "transaction")
+	}
+	defer rows.Close()
+
+	transactions := make(map[string]models.Transaction)
+
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		transactions[transaction.ID] = transaction
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, mapError(err, "transaction")
+	}
+
+	return transactions, nil
+}
+
+func transactionIDs(transactions []models.Transaction) []string {
+	ids := make([]string, 0, len(transactions))
+	for _, transaction := range transactions {
+		ids = append(ids, transaction.ID)
+	}
+	return ids
+}
+
+// uniqueIDs убирает повторы и приводит UUID к виду, в котором их возвращает Postgres.
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+
+	for _, id := range ids {
+		id = strings.ToLower(id)
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+
+	return unique
+}
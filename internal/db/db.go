@@ -2,67 +2,147 @@ package db
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"simple-finance/internal/errs"
 	"simple-finance/internal/models"
 )
 
 type FinanceDB struct {
-	conn *pgx.Conn
+	conn *pgxpool.Pool
 }
 
-func NewFinanceDB(conn *pgx.Conn) *FinanceDB {
+// NewFinanceDB uses a connection pool, so concurrent requests do not share a
+// single connection and a cancelled request releases its connection.
+func NewFinanceDB(conn *pgxpool.Pool) *FinanceDB {
 	return &FinanceDB{
 		conn: conn,
 	}
 }
 
+// transactionColumns перечисляет колонки явно, чтобы новые колонки таблицы
+// не ломали Scan. Автор операции может быть удалён, тогда user_id пустой.
+const transactionColumns = `
+	t.id, t.ledger_id, COALESCE(t.user_id::text, ''), t.amount, t.category_id, t.comment, t.date, t.created_at,
+	t.version, t.deleted_at
+`
+
+func scanTransaction(row pgx.Row) (models.Transaction, error) {
+	var transaction models.Transaction
+
+	err := row.Scan(transactionFields(&transaction)...)
+
+	return transaction, err
+}
+
+// transactionFields returns scan destinations in the order of transactionColumns,
+// for queries that select more columns after them.
+func transactionFields(transaction *models.Transaction) []any {
+	return []any{
+		&transaction.ID,
+		&transaction.LedgerID,
+		&transaction.UserID,
+		&transaction.Amount,
+		&transaction.CategoryID,
+		&transaction.Comment,
+		&transaction.Date,
+		&transaction.CreatedAt,
+		&transaction.Version,
+		&transaction.DeletedAt,
+	}
+}
+
+// InsertTransaction adds a transaction to the ledger on behalf of its author,
+// who has to be an owner or editor. The category must belong to the same ledger.
+// Rules of the ledger assign the category if the client left it empty and add tags.
 func (db *FinanceDB) InsertTransaction(ctx context.Context, transaction models.Transaction) (string, error) {
 	const query = `
-	INSERT INTO transactions (id, user_id, amount, category_id, comment, date, created_at)
-	VALUES($1, $2, $3, $4, $5, $6, NOW())
-	RETURNING id
-	`
+	INSERT INTO transactions AS t (id, ledger_id, user_id, amount, category_id, comment, date, created_at)
+	SELECT $1::uuid, $2::uuid, $3::uuid, $4::double precision, c.id, $5::text, $6::date, NOW()
+	FROM categories c
+	WHERE c.id = $7 AND c.ledger_id = $2 AND c.deleted_at IS NULL
+	RETURNING` + transactionColumns
+
+	err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		err := requireLedgerRole(ctx, tx, transaction.LedgerID, transaction.UserID, ledgerWriters...)
+		if err != nil {
+			return err
+		}
 
-	row := db.conn.QueryRow(ctx, query,
-		transaction.ID,
-		transaction.UserID,
-		transaction.Amount,
-		transaction.CategoryID,
-		transaction.Comment,
-		transaction.Date,
-	)
+		engine, err := ledgerEngine(ctx, tx, transaction.LedgerID)
+		if err != nil {
+			return err
+		}
+
+		tagIDs := assignByRules(engine, &transaction)
+		if transaction.CategoryID == "" {
+			return errCategoryRequired
+		}
+
+		row := tx.QueryRow(ctx, query,
+			transaction.ID,
+			transaction.LedgerID,
+			transaction.UserID,
+			transaction.Amount,
+			transaction.Comment,
+			transaction.Date,
+			transaction.CategoryID,
+		)
 
-	var transactionID string
-	err := row.Scan(&transactionID)
+		inserted, err := scanTransaction(row)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errs.Validation("category_not_found", "category does not exist")
+		}
+		if err != nil {
+			return mapError(err, "transaction")
+		}
+
+		transactionIDs := make([]string, len(tagIDs))
+		for i := range tagIDs {
+			transactionIDs[i] = inserted.ID
+		}
+
+		_, err = attachRuleTags(ctx, tx, transactionIDs, tagIDs)
+		if err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "transaction",
+			entityID: inserted.ID,
+			action:   models.AuditCreate,
+			ledgerID: inserted.LedgerID,
+			after:    inserted,
+		})
+	})
+	if err != nil {
+		return "", err
+	}
 
-	return transactionID, err
+	return transaction.ID, nil
 }
 
-func (db *FinanceDB) GetTransactions(ctx context.Context, userID string) ([]models.Transaction, error) {
-	const query = "SELECT * FROM transactions WHERE user_id = $1"
+func (db *FinanceDB) GetTransactions(ctx context.Context, userID, ledgerID string) ([]models.Transaction, error) {
+	const query = "SELECT" + transactionColumns + "FROM transactions t WHERE t.ledger_id = $1 AND t.deleted_at IS NULL"
 
-	rows, err := db.conn.Query(ctx, query, userID)
+	err := requireLedgerRole(ctx, db.conn, ledgerID, userID)
 	if err != nil {
 		return nil, err
 	}
 
+	rows, err := db.conn.Query(ctx, query, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
 	transactions := make([]models.Transaction, 0)
 
 	for rows.Next() {
-		var transaction models.Transaction
-
-		err := rows.Scan(
-			&transaction.ID,
-			&transaction.UserID,
-			&transaction.Amount,
-			&transaction.CategoryID,
-			&transaction.Comment,
-			&transaction.Date,
-			&transaction.CreatedAt,
-		)
-
+		transaction, err := scanTransaction(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -70,33 +150,146 @@ func (db *FinanceDB) GetTransactions(ctx context.Context, userID string) ([]mode
 		transactions = append(transactions, transaction)
 	}
 
-	return transactions, nil
+	return transactions, rows.Err()
 }
 
+// GetTransactionByID returns the transaction if the user is a member of its ledger.
 func (db *FinanceDB) GetTransactionByID(ctx context.Context, userID string, transactionID string) (models.Transaction, error) {
-	const query = "SELECT * FROM transactions WHERE user_id = $1 AND id = $2 LIMIT 1"
+	const query = "SELECT" + transactionColumns + `
+		FROM transactions t
+		JOIN ledger_members m ON m.ledger_id = t.ledger_id AND m.user_id = $1
+		WHERE t.id = $2 AND t.deleted_at IS NULL
+		LIMIT 1
+	`
 
-	row := db.conn.QueryRow(ctx, query, userID, transactionID)
-	var transaction models.Transaction
+	transaction, err := scanTransaction(db.conn.QueryRow(ctx, query, userID, transactionID))
 
-	err := row.Scan(
-		&transaction.ID,
-		&transaction.UserID,
-		&transaction.Amount,
-		&transaction.CategoryID,
-		&transaction.Comment,
-		&transaction.Date,
-		&transaction.CreatedAt,
-	)
+	return transaction, mapError(err, "transaction")
+}
 
-	return transaction, err
+// lockTransaction reads the transaction for a change and locks it until the
+// transaction ends. deleted selects between live transactions and the trash.
+func lockTransaction(ctx context.Context, tx pgx.Tx, userID, transactionID string, deleted bool) (models.Transaction, error) {
+	const query = "SELECT" + transactionColumns + `
+		FROM transactions t
+		JOIN ledger_members m ON m.ledger_id = t.ledger_id AND m.user_id = $1
+		WHERE t.id = $2 AND (t.deleted_at IS NOT NULL) = $3
+		FOR UPDATE OF t
+	`
+
+	transaction, err := scanTransaction(tx.QueryRow(ctx, query, userID, transactionID, deleted))
+
+	return transaction, mapError(err, "transaction")
 }
 
-func (db *FinanceDB) DeleteTransactionByID(ctx context.Context, userID string, transactionID string) error {
-	const query = "DELETE FROM transactions WHERE user_id = $1 AND id = $2"
+// UpdateTransaction changes the given fields of the transaction if the user
+// is an owner or editor of its ledger and version matches the current one.
+func (db *FinanceDB) UpdateTransaction(
+	ctx context.Context,
+	userID, transactionID string,
+	input models.UpdateTransactionInput,
+	version int64,
+) (models.Transaction, error) {
+	const categoryExists = `
+		SELECT EXISTS (SELECT 1 FROM categories WHERE id = $1 AND ledger_id = $2 AND deleted_at IS NULL)
+	`
+	const query = `
+		UPDATE transactions AS t
+		SET amount = COALESCE($2::double precision, amount),
+		    category_id = COALESCE($3::uuid, category_id),
+		    comment = COALESCE($4::text, comment),
+		    date = COALESCE($5::date, date),
+		    version = version + 1
+		WHERE t.id = $1
+		RETURNING` + transactionColumns
+
+	var updated models.Transaction
+
+	err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		current, err := lockTransaction(ctx, tx, userID, transactionID, false)
+		if err != nil {
+			return err
+		}
+
+		err = requireLedgerRole(ctx, tx, current.LedgerID, userID, ledgerWriters...)
+		if err != nil {
+			return err
+		}
 
-	_, err := db.conn.Exec(ctx, query, userID, transactionID)
-	return err
+		err = checkVersion(version, current.Version)
+		if err != nil {
+			return err
+		}
+
+		if input.CategoryID != nil {
+			var exists bool
+			err = tx.QueryRow(ctx, categoryExists, *input.CategoryID, current.LedgerID).Scan(&exists)
+			if err != nil {
+				return mapError(err, "category")
+			}
+			if !exists {
+				return errs.Validation("category_not_found", "category does not exist")
+			}
+		}
+
+		row := tx.QueryRow(ctx, query, transactionID, input.Amount, input.CategoryID, input.Comment, input.Date)
+		updated, err = scanTransaction(row)
+		if err != nil {
+			return mapError(err, "transaction")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "transaction",
+			entityID: updated.ID,
+			action:   models.AuditUpdate,
+			ledgerID: updated.LedgerID,
+			before:   current,
+			after:    updated,
+		})
+	})
+
+	return updated, err
+}
+
+// DeleteTransactionByID moves the transaction to the trash if the user is an
+// owner or editor of its ledger and version matches the current one.
+func (db *FinanceDB) DeleteTransactionByID(ctx context.Context, userID string, transactionID string, version int64) error {
+	const query = `
+		UPDATE transactions AS t
+		SET deleted_at = NOW(), version = version + 1
+		WHERE t.id = $1
+		RETURNING` + transactionColumns
+
+	return pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		transaction, err := lockTransaction(ctx, tx, userID, transactionID, false)
+		if err != nil {
+			return err
+		}
+
+		err = requireLedgerRole(ctx, tx, transaction.LedgerID, userID, ledgerWriters...)
+		if err != nil {
+			return err
+		}
+
+		err = checkVersion(version, transaction.Version)
+		if err != nil {
+			return err
+		}
+
+		deleted, err := scanTransaction(tx.QueryRow(ctx, query, transactionID))
+		if err != nil {
+			return mapError(err, "transaction")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "transaction",
+			entityID: deleted.ID,
+			action:   models.AuditDelete,
+			ledgerID: deleted.LedgerID,
+			before:   transaction,
+			after:    deleted,
+		})
+	})
 }
 
 func (db *FinanceDB) GetUserID(ctx context.Context, username string) (string, error) {
@@ -107,40 +300,71 @@ func (db *FinanceDB) GetUserID(ctx context.Context, username string) (string, er
 
 	err := row.Scan(&userID)
 
-	return userID, err
+	return userID, mapError(err, "user")
 }
 
+// InsertUser creates the user together with their personal ledger.
 func (db *FinanceDB) InsertUser(ctx context.Context, userInfo models.UserInfo) (models.UserInfo, error) {
 	const query = `
 		INSERT INTO users(id, email, username, hash_pass, created_at)
-		VALUES($1, $2, $3, $4, NOW())
-		RETURNING created_at
+		VALUES($1, lower(trim($2)), $3, $4, NOW())
+		RETURNING email, role, created_at
 	`
 
-	row := db.conn.QueryRow(ctx, query,
-		userInfo.ID,
-		userInfo.Email,
-		userInfo.UserName,
-		userInfo.Password,
+	var (
+		email     string
+		role      string
+		createdAt time.Time
 	)
 
-	var createdAt time.Time
-	err := row.Scan(&createdAt)
+	err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, query,
+			userInfo.ID,
+			userInfo.Email,
+			userInfo.UserName,
+			userInfo.Password,
+		)
+
+		err := row.Scan(&email, &role, &createdAt)
+		if err != nil {
+			return mapError(err, "user")
+		}
+
+		_, err = insertLedger(ctx, tx, userInfo.ID, userInfo.ID, personalLedgerName)
+		if err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "user",
+			entityID: userInfo.ID,
+			action:   models.AuditCreate,
+			userID:   userInfo.ID,
+			after: models.UserInfoWithoutPass{
+				ID:        userInfo.ID,
+				Email:     email,
+				UserName:  userInfo.UserName,
+				Role:      role,
+				CreatedAt: createdAt,
+			},
+		})
+	})
 	if err != nil {
 		return models.UserInfo{}, err
 	}
 
 	return models.UserInfo{
 		ID:        userInfo.ID,
-		Email:     userInfo.Email,
+		Email:     email,
 		UserName:  userInfo.UserName,
+		Role:      role,
 		CreatedAt: createdAt,
 	}, nil
 }
 
 func (db *FinanceDB) GetUserInfo(ctx context.Context, userName string) (models.UserInfo, error) {
 	const query = `
-		SELECT id, email, username, hash_pass, created_at
+		SELECT id, email, username, hash_pass, role, created_at
 		FROM users
 		WHERE username = $1
 		LIMIT 1
@@ -152,27 +376,324 @@ func (db *FinanceDB) GetUserInfo(ctx context.Context, userName string) (models.U
 		&userInfo.Email,
 		&userInfo.UserName,
 		&userInfo.Password,
+		&userInfo.Role,
 		&userInfo.CreatedAt,
 	)
 
-	return userInfo, err
+	return userInfo, mapError(err, "user")
+}
+
+// GetUserInfoByEmail looks the user up by email ignoring case.
+func (db *FinanceDB) GetUserInfoByEmail(ctx context.Context, email string) (models.UserInfo, error) {
+	const query = `
+		SELECT id, email, username, hash_pass, role, created_at
+		FROM users
+		WHERE lower(email) = lower(trim($1))
+		LIMIT 1
+	`
+	row := db.conn.QueryRow(ctx, query, email)
+	var userInfo models.UserInfo
+	err := row.Scan(
+		&userInfo.ID,
+		&userInfo.Email,
+		&userInfo.UserName,
+		&userInfo.Password,
+		&userInfo.Role,
+		&userInfo.CreatedAt,
+	)
+
+	return userInfo, mapError(err, "user")
 }
 
-func (db *FinanceDB) GetUserById(ctx context.Context, id string) (models.UserInfo, error) {
+func (db *FinanceDB) GetUserById(ctx context.Context, id string) (models.UserInfoWithoutPass, error) {
 	const query = `
-		SELECT id, email, username,  created_at
+		SELECT id, email, email_verified_at IS NOT NULL, username, role, created_at, version
 		FROM users
 		WHERE id = $1
 		LIMIT 1
 	`
 	row := db.conn.QueryRow(ctx, query, id)
-	var userInfo models.UserInfo
+	var userInfo models.UserInfoWithoutPass
 	err := row.Scan(
 		&userInfo.ID,
 		&userInfo.Email,
+		&userInfo.EmailVerified,
 		&userInfo.UserName,
+		&userInfo.Role,
 		&userInfo.CreatedAt,
+		&userInfo.Version,
 	)
 
+	return userInfo, mapError(err, "user")
+}
+
+func (db *FinanceDB) GetPasswordHash(ctx context.Context, userID string) (string, error) {
+	const query = `SELECT hash_pass FROM users WHERE id = $1`
+
+	var hashPass string
+	err := db.conn.QueryRow(ctx, query, userID).Scan(&hashPass)
+
+	return hashPass, mapError(err, "user")
+}
+
+// UpdateUser changes username and email of the user. Nil values are left as is.
+// A new email has to be verified again.
+func (db *FinanceDB) UpdateUser(
+	ctx context.Context,
+	userID string,
+	userName, email *string,
+	version int64,
+) (models.UserInfoWithoutPass, error) {
+	const query = `
+		UPDATE users
+		SET username = COALESCE($2, username),
+		    email = COALESCE(lower(trim($3::text)), email),
+		    email_verified_at = CASE
+		        WHEN $3::text IS NOT NULL AND lower(trim($3::text)) <> email THEN NULL
+		        ELSE email_verified_at
+		    END,
+		    version = version + 1
+		WHERE id = $1
+		RETURNING id, email, email_verified_at IS NOT NULL, username, role, created_at, version
+	`
+
+	var userInfo models.UserInfoWithoutPass
+
+	err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		before, err := getUserForUpdate(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		err = checkVersion(version, before.Version)
+		if err != nil {
+			return err
+		}
+
+		row := tx.QueryRow(ctx, query, userID, userName, email)
+		err = row.Scan(
+			&userInfo.ID,
+			&userInfo.Email,
+			&userInfo.EmailVerified,
+			&userInfo.UserName,
+			&userInfo.Role,
+			&userInfo.CreatedAt,
+			&userInfo.Version,
+		)
+		if err != nil {
+			return mapError(err, "user")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "user",
+			entityID: userID,
+			action:   models.AuditUpdate,
+			userID:   userID,
+			before:   before,
+			after:    userInfo,
+		})
+	})
+
 	return userInfo, err
 }
+
+// getUserForUpdate reads the profile and locks the row until the transaction ends.
+func getUserForUpdate(ctx context.Context, tx pgx.Tx, userID string) (models.UserInfoWithoutPass, error) {
+	const query = `
+		SELECT id, email, email_verified_at IS NOT NULL, username, role, created_at, version
+		FROM users
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	var userInfo models.UserInfoWithoutPass
+	err := tx.QueryRow(ctx, query, userID).Scan(
+		&userInfo.ID,
+		&userInfo.Email,
+		&userInfo.EmailVerified,
+		&userInfo.UserName,
+		&userInfo.Role,
+		&userInfo.CreatedAt,
+		&userInfo.Version,
+	)
+
+	return userInfo, mapError(err, "user")
+}
+
+// UpdatePassword sets a new password hash. The hash itself never gets
+// into the audit log.
+func (db *FinanceDB) UpdatePassword(ctx context.Context, userID, hashPass string) error {
+	const query = `UPDATE users SET hash_pass = $2 WHERE id = $1`
+
+	return pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, query, userID, hashPass)
+		if err != nil {
+			return mapError(err, "user")
+		}
+
+		if tag.RowsAffected() == 0 {
+			return errs.NotFound("user_not_found", "user not found")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "password",
+			entityID: userID,
+			action:   models.AuditUpdate,
+			userID:   userID,
+		})
+	})
+}
+
+// DeleteUser removes the user together with all of their data in one transaction.
+// Ledgers where the user is the only member are deleted. In shared ledgers the
+// user's transactions stay without an author, and if the user was the only
+// owner, the longest-standing member becomes one.
+func (db *FinanceDB) DeleteUser(ctx context.Context, userID string, version int64) error {
+	queries := []string{
+		`UPDATE ledger_members m
+		SET role = 'owner'
+		FROM (
+			SELECT DISTINCT ON (o.ledger_id) o.ledger_id, o.user_id
+			FROM ledger_members o
+			WHERE o.user_id <> $1
+			  AND o.ledger_id IN (SELECT ledger_id FROM ledger_members WHERE user_id = $1 AND role = 'owner')
+			  AND NOT EXISTS (
+				SELECT 1 FROM ledger_members x
+				WHERE x.ledger_id = o.ledger_id AND x.user_id <> $1 AND x.role = 'owner'
+			  )
+			ORDER BY o.ledger_id, o.created_at, o.user_id
+		) heir
+		WHERE m.ledger_id = heir.ledger_id AND m.user_id = heir.user_id`,
+		`DELETE FROM ledgers l
+		WHERE EXISTS (SELECT 1 FROM ledger_members WHERE ledger_id = l.id AND user_id = $1)
+		  AND NOT EXISTS (SELECT 1 FROM ledger_members WHERE ledger_id = l.id AND user_id <> $1)`,
+		`DELETE FROM transaction_tags WHERE tag_id IN (SELECT id FROM tags WHERE user_id = $1)`,
+		// Взносы в цели остаются в книгах без автора.
+		`DELETE FROM incomes WHERE user_id = $1 AND goal_id IS NULL`,
+		`DELETE FROM tags WHERE user_id = $1`,
+	}
+
+	return pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		before, err := getUserForUpdate(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		err = checkVersion(version, before.Version)
+		if err != nil {
+			return err
+		}
+
+		for _, query := range queries {
+			_, err := tx.Exec(ctx, query, userID)
+			if err != nil {
+				return mapError(err, "user")
+			}
+		}
+
+		_, err = tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID)
+		if err != nil {
+			return mapError(err, "user")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "user",
+			entityID: userID,
+			action:   models.AuditDelete,
+			userID:   userID,
+			before:   before,
+		})
+	})
+}
+
+// VerifyEmail marks the email of the user as verified. It fails if the user
+// has changed the email since the verification was requested.
+func (db *FinanceDB) VerifyEmail(ctx context.Context, userID, email string) error {
+	const query = `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, NOW()),
+		    version = version + CASE WHEN email_verified_at IS NULL THEN 1 ELSE 0 END
+		WHERE id = $1 AND lower(email) = lower($2)
+	`
+
+	tag, err := db.conn.Exec(ctx, query, userID, email)
+	if err != nil {
+		return mapError(err, "user")
+	}
+
+	if tag.RowsAffected() == 0 {
+		return errs.Validation("verification_outdated", "email has changed since verification was requested")
+	}
+
+	return nil
+}
+
+func (db *FinanceDB) IsEmailVerified(ctx context.Context, userID string) (bool, error) {
+	const query = `SELECT email_verified_at IS NOT NULL FROM users WHERE id = $1`
+
+	var verified bool
+	err := db.conn.QueryRow(ctx, query, userID).Scan(&verified)
+
+	return verified, mapError(err, "user")
+}
+
+func (db *FinanceDB) InsertPasswordResetToken(ctx context.Context, userID, tokenHash string, expiresAt time.Time) error {
+	const query = `
+		INSERT INTO password_reset_tokens (token_hash, user_id, expires_at, created_at)
+		VALUES ($1, $2, $3, NOW())
+	`
+
+	_, err := db.conn.Exec(ctx, query, tokenHash, userID, expiresAt)
+	return mapError(err, "password_reset_token")
+}
+
+// ResetPassword uses the reset token and sets the new password in one
+// transaction. All other outstanding tokens of the user are burnt as well.
+func (db *FinanceDB) ResetPassword(ctx context.Context, tokenHash, hashPass string) (string, error) {
+	const useToken = `
+		UPDATE password_reset_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id
+	`
+	const burnOthers = `
+		UPDATE password_reset_tokens
+		SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL
+	`
+	const updatePassword = `UPDATE users SET hash_pass = $2 WHERE id = $1`
+
+	var userID string
+	err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, useToken, tokenHash).Scan(&userID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return errs.Validation("reset_token_invalid", "reset token is invalid, used or expired")
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, burnOthers, userID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, updatePassword, userID, hashPass)
+		if err != nil {
+			return err
+		}
+
+		// Сброс делает анонимный клиент, поэтому автором считаем владельца токена.
+		meta := auditMetaFromContext(ctx)
+		meta.ActorID = userID
+
+		return writeAudit(WithAuditMeta(ctx, meta), tx, auditRecord{
+			entity:   "password",
+			entityID: userID,
+			action:   models.AuditUpdate,
+			userID:   userID,
+		})
+	})
+
+	return userID, err
+}
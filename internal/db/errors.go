@@ -0,0 +1,64 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"simple-finance/internal/errs"
+)
+
+// Коды ошибок Postgres, которые мы превращаем в доменные ошибки.
+const (
+	pgUniqueViolation           = "23505"
+	pgForeignKeyViolation       = "23503"
+	pgNotNullViolation          = "23502"
+	pgCheckViolation            = "23514"
+	pgInvalidTextRepresentation = "22P02"
+)
+
+// constraintErrors описывает ошибки для ограничений, о которых клиенту нужно
+// сообщить конкретнее, чем общим кодом ресурса.
+var constraintErrors = map[string]error{
+	"users_username_unique":            errs.Conflict("username_taken", "username is already taken"),
+	"users_email_unique":               errs.Conflict("email_taken", "email is already registered"),
+	"users_email_lower_unique":         errs.Conflict("email_taken", "email is already registered"),
+	"transactions_category_id_foreign": errs.Validation("category_not_found", "category does not exist"),
+	"transaction_tags_pkey":            errs.Conflict("tag_already_attached", "tag is already attached to the transaction"),
+	"transaction_tags_tag_id_foreign":  errs.Validation("tag_not_found", "tag does not exist"),
+}
+
+// mapError converts pgx errors into domain errors from the errs package.
+// entity names the resource the query works with and prefixes error codes,
+// e.g. "transaction" gives "transaction_not_found".
+func mapError(err error, entity string) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errs.NotFound(entity+"_not_found", entity+" not found")
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	if constraintErr, ok := constraintErrors[pgErr.ConstraintName]; ok {
+		return constraintErr
+	}
+
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		return errs.Conflict(entity+"_already_exists", entity+" already exists")
+	case pgForeignKeyViolation:
+		return errs.Validation(entity+"_invalid_reference", "referenced resource does not exist")
+	case pgNotNullViolation, pgCheckViolation:
+		return errs.Validation(entity+"_invalid", "invalid "+entity+" data")
+	case pgInvalidTextRepresentation:
+		return errs.Validation(entity+"_invalid_id", "invalid identifier format")
+	}
+
+	return err
+}
@@ -0,0 +1,509 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"simple-finance/internal/errs"
+	"simple-finance/internal/goals"
+	"simple-finance/internal/models"
+)
+
+// goalSelect читает цель вместе с суммами взносов: всех и за последние
+// goals.RecentDays дней ($1), из которых считается прогресс.
+const goalSelect = `
+	SELECT
+		g.id, g.ledger_id, g.user_id::text, g.name, g.target_amount, g.target_date, g.start_date,
+		g.category_id::text, g.created_at, g.version, s.saved, s.recent
+	FROM goals g
+	CROSS JOIN LATERAL (
+		SELECT
+			COALESCE(SUM(c.amount), 0) AS saved,
+			COALESCE(SUM(c.amount) FILTER (WHERE c.date > CURRENT_DATE - $1::int), 0) AS recent
+		FROM (
+			SELECT i.amount, i.date
+			FROM incomes i
+			WHERE i.goal_id = g.id AND i.deleted_at IS NULL
+			UNION ALL
+			SELECT t.amount, t.date
+			FROM transactions t
+			WHERE t.ledger_id = g.ledger_id AND t.category_id = g.category_id
+			  AND t.deleted_at IS NULL AND t.date >= g.start_date
+		) c
+	) s
+`
+
+func scanGoal(row pgx.Row) (models.Goal, error) {
+	var goal models.Goal
+	var saved, recent float64
+
+	err := row.Scan(
+		&goal.ID,
+		&goal.LedgerID,
+		&goal.UserID,
+		&goal.Name,
+		&goal.TargetAmount,
+		&goal.TargetDate,
+		&goal.StartDate,
+		&goal.CategoryID,
+		&goal.CreatedAt,
+		&goal.Version,
+		&saved,
+		&recent,
+	)
+	if err != nil {
+		return goal, err
+	}
+
+	goal.Progress = goals.Compute(goal, saved, recent, time.Now())
+
+	return goal, nil
+}
+
+// GetGoals returns savings goals of the ledger with their progress.
+func (db *FinanceDB) GetGoals(ctx context.Context, userID, ledgerID string) ([]models.Goal, error) {
+	const query = goalSelect + `
+		WHERE g.ledger_id = $2
+		ORDER BY g.created_at, g.id
+	`
+
+	err := requireLedgerRole(ctx, db.conn, ledgerID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.Query(ctx, query, goals.RecentDays, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]models.Goal, 0)
+
+	for rows.Next() {
+		goal, err := scanGoal(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, goal)
+	}
+
+	return list, rows.Err()
+}
+
+func getGoal(ctx context.Context, q querier, ledgerID, goalID string) (models.Goal, error) {
+	const query = goalSelect + `WHERE g.id = $2 AND g.ledger_id = $3`
+
+	goal, err := scanGoal(q.QueryRow(ctx, query, goals.RecentDays, goalID, ledgerID))
+
+	return goal, mapError(err, "goal")
+}
+
+// GetGoal returns the goal if the user is a member of its ledger.
+func (db *FinanceDB) GetGoal(ctx context.Context, userID, ledgerID, goalID string) (models.Goal, error) {
+	err := requireLedgerRole(ctx, db.conn, ledgerID, userID)
+	if err != nil {
+		return models.Goal{}, err
+	}
+
+	return getGoal(ctx, db.conn, ledgerID, goalID)
+}
+
+// requireLedgerCategory checks that the category is a live category of the ledger.
+func requireLedgerCategory(ctx context.Context, q querier, ledgerID, categoryID string) error {
+	const query = `
+		SELECT EXISTS (SELECT 1 FROM categories WHERE id = $1 AND ledger_id = $2 AND deleted_at IS NULL)
+	`
+
+	var exists bool
+	err := q.QueryRow(ctx, query, categoryID, ledgerID).Scan(&exists)
+	if err != nil {
+		return mapError(err, "category")
+	}
+
+	if !exists {
+		return errs.Validation("category_not_found", "category does not exist")
+	}
+
+	return nil
+}
+
+// InsertGoal adds a savings goal to the ledger, starting today unless the
+// goal has a start date. Requires the owner or editor role.
+func (db *FinanceDB) InsertGoal(ctx context.Context, userID string, goal models.Goal) (models.Goal, error) {
+	const query = `
+		INSERT INTO goals (id, ledger_id, user_id, name, target_amount, target_date, start_date, category_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6::date, $7::date, $8, NOW())
+	`
+
+	goal, err := goals.Resolve(goal, time.Now())
+	if err != nil {
+		return models.Goal{}, err
+	}
+
+	var inserted models.Goal
+
+	err = pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		err := requireLedgerRole(ctx, tx, goal.LedgerID, userID, ledgerWriters...)
+		if err != nil {
+			return err
+		}
+
+		if goal.CategoryID != nil {
+			err = requireLedgerCategory(ctx, tx, goal.LedgerID, *goal.CategoryID)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(ctx, query,
+			goal.ID,
+			goal.LedgerID,
+			userID,
+			goal.Name,
+			goal.TargetAmount,
+			goal.TargetDate,
+			goal.StartDate,
+			goal.CategoryID,
+		)
+		if err != nil {
+			return mapError(err, "goal")
+		}
+
+		inserted, err = getGoal(ctx, tx, goal.LedgerID, goal.ID)
+		if err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "goal",
+			entityID: inserted.ID,
+			action:   models.AuditCreate,
+			ledgerID: inserted.LedgerID,
+			after:    inserted,
+		})
+	})
+
+	return inserted, err
+}
+
+func lockGoal(ctx context.Context, tx pgx.Tx, ledgerID, goalID string) (models.Goal, error) {
+	const query = goalSelect + `
+		WHERE g.id = $2 AND g.ledger_id = $3
+		FOR UPDATE OF g
+	`
+
+	goal, err := scanGoal(tx.QueryRow(ctx, query, goals.RecentDays, goalID, ledgerID))
+
+	return goal, mapError(err, "goal")
+}
+
+// UpdateGoal replaces the goal definition if version matches the current one.
+// A goal without a start date keeps the stored one.
+func (db *FinanceDB) UpdateGoal(ctx context.Context, userID string, goal models.Goal, version int64) (models.Goal, error) {
+	const query = `
+		UPDATE goals
+		SET name = $3, target_amount = $4, target_date = $5::date, start_date = $6::date, category_id = $7,
+		    version = version + 1
+		WHERE id = $1 AND ledger_id = $2
+	`
+
+	var updated models.Goal
+
+	err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		err := requireLedgerRole(ctx, tx, goal.LedgerID, userID, ledgerWriters...)
+		if err != nil {
+			return err
+		}
+
+		current, err := lockGoal(ctx, tx, goal.LedgerID, goal.ID)
+		if err != nil {
+			return err
+		}
+
+		err = checkVersion(version, current.Version)
+		if err != nil {
+			return err
+		}
+
+		goal, err = goals.Resolve(goal, current.StartDate)
+		if err != nil {
+			return err
+		}
+
+		if goal.CategoryID != nil {
+			err = requireLedgerCategory(ctx, tx, goal.LedgerID, *goal.CategoryID)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(ctx, query,
+			goal.ID,
+			goal.LedgerID,
+			goal.Name,
+			goal.TargetAmount,
+			goal.TargetDate,
+			goal.StartDate,
+			goal.CategoryID,
+		)
+		if err != nil {
+			return mapError(err, "goal")
+		}
+
+		updated, err = getGoal(ctx, tx, goal.LedgerID, goal.ID)
+		if err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "goal",
+			entityID: updated.ID,
+			action:   models.AuditUpdate,
+			ledgerID: updated.LedgerID,
+			before:   current,
+			after:    updated,
+		})
+	})
+
+	return updated, err
+}
+
+// DeleteGoal removes the goal if version matches the current one. Incomes
+// recorded for it stay as ordinary incomes.
+func (db *FinanceDB) DeleteGoal(ctx context.Context, userID, ledgerID, goalID string, version int64) error {
+	return pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		err := requireLedgerRole(ctx, tx, ledgerID, userID, ledgerWriters...)
+		if err != nil {
+			return err
+		}
+
+		goal, err := lockGoal(ctx, tx, ledgerID, goalID)
+		if err != nil {
+			return err
+		}
+
+		err = checkVersion(version, goal.Version)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `DELETE FROM goals WHERE id = $1`, goalID)
+		if err != nil {
+			return mapError(err, "goal")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "goal",
+			entityID: goal.ID,
+			action:   models.AuditDelete,
+			ledgerID: goal.LedgerID,
+			before:   goal,
+		})
+	})
+}
+
+// GetContributions returns incomes recorded for the goal and transactions of
+// its category, newest first.
+func (db *FinanceDB) GetContributions(ctx context.Context, userID, ledgerID, goalID string) ([]models.Contribution, error) {
+	const query = `
+		SELECT` + contributionColumns + `
+		FROM incomes i
+		JOIN goals g ON g.id = i.goal_id
+		WHERE g.id = $1 AND g.ledger_id = $2 AND i.deleted_at IS NULL
+		UNION ALL
+		SELECT
+			t.id, 'transfer', COALESCE(t.user_id::text, ''), t.amount, t.comment, t.date, t.created_at,
+			g.id::text, t.deleted_at
+		FROM transactions t
+		JOIN goals g ON g.ledger_id = t.ledger_id AND g.category_id = t.category_id
+		WHERE g.id = $1 AND g.ledger_id = $2 AND t.deleted_at IS NULL AND t.date >= g.start_date
+		ORDER BY 6 DESC, 7 DESC
+	`
+
+	_, err := db.GetGoal(ctx, userID, ledgerID, goalID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.Query(ctx, query, goalID, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contributions := make([]models.Contribution, 0)
+
+	for rows.Next() {
+		contribution, err := scanContribution(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		contributions = append(contributions, contribution)
+	}
+
+	return contributions, rows.Err()
+}
+
+// AddContribution records an income of the user towards the goal. Requires
+// the owner or editor role in the goal's ledger.
+func (db *FinanceDB) AddContribution(
+	ctx context.Context,
+	userID, ledgerID, goalID string,
+	input models.ContributionInput,
+) (models.Contribution, error) {
+	const query = `
+		INSERT INTO incomes AS i (id, user_id, amount, comment, date, created_at, goal_id)
+		VALUES ($1, $2, $3, $4, $5::date, NOW(), $6)
+		RETURNING` + contributionColumns + `
+	`
+
+	var contribution models.Contribution
+
+	err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		err := requireLedgerRole(ctx, tx, ledgerID, userID, ledgerWriters...)
+		if err != nil {
+			return err
+		}
+
+		// Блокируем цель, чтобы её не удалили, пока записывается взнос.
+		_, err = lockGoal(ctx, tx, ledgerID, goalID)
+		if err != nil {
+			return err
+		}
+
+		contribution, err = scanContribution(
+			tx.QueryRow(ctx, query, uuid.New().String(), userID, input.Amount, input.Comment, input.Date, goalID),
+		)
+		if err != nil {
+			return mapError(err, "income")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "income",
+			entityID: contribution.ID,
+			action:   models.AuditCreate,
+			userID:   userID,
+			ledgerID: ledgerID,
+			after:    contribution,
+		})
+	})
+
+	return contribution, err
+}
+
+// contributionColumns читает доход, записанный на цель, в виде взноса.
+// У взноса удалённого пользователя автора нет.
+const contributionColumns = `
+	i.id, 'income', COALESCE(i.user_id::text, ''), i.amount, i.comment, i.date, i.created_at, i.goal_id::text, i.deleted_at
+`
+
+func scanContribution(row pgx.Row) (models.Contribution, error) {
+	var contribution models.Contribution
+
+	err := row.Scan(
+		&contribution.ID,
+		&contribution.Source,
+		&contribution.UserID,
+		&contribution.Amount,
+		&contribution.Comment,
+		&contribution.Date,
+		&contribution.CreatedAt,
+		&contribution.GoalID,
+		&contribution.DeletedAt,
+	)
+
+	return contribution, err
+}
+
+// lockContribution reads an income recorded for the goal of the ledger for a
+// change. deleted selects between live incomes and the trash.
+func lockContribution(ctx context.Context, tx pgx.Tx, ledgerID, goalID, contributionID string, deleted bool) (models.Contribution, error) {
+	const query = "SELECT" + contributionColumns + `
+		FROM incomes i
+		JOIN goals g ON g.id = i.goal_id
+		WHERE i.id = $1 AND g.id = $2 AND g.ledger_id = $3 AND (i.deleted_at IS NOT NULL) = $4
+		FOR UPDATE OF i
+	`
+
+	contribution, err := scanContribution(tx.QueryRow(ctx, query, contributionID, goalID, ledgerID, deleted))
+
+	return contribution, mapError(err, "contribution")
+}
+
+// DeleteContribution moves an income recorded for the goal to the trash.
+// Transfers are transactions and are deleted as such.
+func (db *FinanceDB) DeleteContribution(ctx context.Context, userID, ledgerID, goalID, contributionID string) error {
+	const query = `
+		UPDATE incomes i
+		SET deleted_at = NOW()
+		WHERE i.id = $1
+		RETURNING` + contributionColumns
+
+	return pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		err := requireLedgerRole(ctx, tx, ledgerID, userID, ledgerWriters...)
+		if err != nil {
+			return err
+		}
+
+		current, err := lockContribution(ctx, tx, ledgerID, goalID, contributionID, false)
+		if err != nil {
+			return err
+		}
+
+		deleted, err := scanContribution(tx.QueryRow(ctx, query, contributionID))
+		if err != nil {
+			return mapError(err, "contribution")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "income",
+			entityID: deleted.ID,
+			action:   models.AuditDelete,
+			userID:   deleted.UserID,
+			ledgerID: ledgerID,
+			before:   current,
+			after:    deleted,
+		})
+	})
+}
+
+// RestoreContribution returns an income recorded for the goal from the trash.
+func (db *FinanceDB) RestoreContribution(ctx context.Context, userID, ledgerID, goalID, contributionID string) error {
+	const query = `
+		UPDATE incomes i
+		SET deleted_at = NULL
+		WHERE i.id = $1
+		RETURNING` + contributionColumns
+
+	return pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		err := requireLedgerRole(ctx, tx, ledgerID, userID, ledgerWriters...)
+		if err != nil {
+			return err
+		}
+
+		trashed, err := lockContribution(ctx, tx, ledgerID, goalID, contributionID, true)
+		if err != nil {
+			return err
+		}
+
+		restored, err := scanContribution(tx.QueryRow(ctx, query, contributionID))
+		if err != nil {
+			return mapError(err, "contribution")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "income",
+			entityID: restored.ID,
+			action:   models.AuditRestore,
+			userID:   restored.UserID,
+			ledgerID: ledgerID,
+			before:   trashed,
+			after:    restored,
+		})
+	})
+}
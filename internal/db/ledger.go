@@ -0,0 +1,558 @@
+package db
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"simple-finance/internal/errs"
+	"simple-finance/internal/models"
+)
+
+const personalLedgerName = "Personal"
+
+// Роли, которым разрешено менять данные книги.
+var ledgerWriters = []string{models.LedgerOwner, models.LedgerEditor}
+
+// querier позволяет проверять права как в транзакции, так и вне её.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// requireLedgerRole checks that the user is a member of the ledger with one
+// of the roles. Non-members get not found, so ledger IDs cannot be probed.
+func requireLedgerRole(ctx context.Context, q querier, ledgerID, userID string, roles ...string) error {
+	const query = `SELECT role FROM ledger_members WHERE ledger_id = $1 AND user_id = $2`
+
+	var role string
+	err := q.QueryRow(ctx, query, ledgerID, userID).Scan(&role)
+	if err != nil {
+		return mapError(err, "ledger")
+	}
+
+	if len(roles) > 0 && !slices.Contains(roles, role) {
+		return errs.Forbidden("ledger_permission_denied", "your role in the ledger does not allow this action")
+	}
+
+	return nil
+}
+
+// ensureOwnerLeft не даёт оставить книгу без владельца.
+func ensureOwnerLeft(ctx context.Context, q querier, ledgerID string) error {
+	const query = `SELECT EXISTS (SELECT 1 FROM ledger_members WHERE ledger_id = $1 AND role = 'owner')`
+
+	var exists bool
+	err := q.QueryRow(ctx, query, ledgerID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return errs.Conflict("last_ledger_owner", "a ledger must have at least one owner")
+	}
+
+	return nil
+}
+
+func insertLedger(ctx context.Context, tx pgx.Tx, ledgerID, ownerID, name string) (models.Ledger, error) {
+	const insertLedger = `INSERT INTO ledgers (id, name, created_at) VALUES ($1, $2, NOW()) RETURNING created_at, version`
+	const insertOwner = `
+		INSERT INTO ledger_members (ledger_id, user_id, role, created_at)
+		VALUES ($1, $2, 'owner', NOW())
+	`
+
+	ledger := models.Ledger{ID: ledgerID, Name: name, Role: models.LedgerOwner}
+
+	err := tx.QueryRow(ctx, insertLedger, ledgerID, name).Scan(&ledger.CreatedAt, &ledger.Version)
+	if err != nil {
+		return models.Ledger{}, mapError(err, "ledger")
+	}
+
+	_, err = tx.Exec(ctx, insertOwner, ledgerID, ownerID)
+	if err != nil {
+		return models.Ledger{}, mapError(err, "ledger")
+	}
+
+	return ledger, nil
+}
+
+func (db *FinanceDB) CreateLedger(ctx context.Context, userID, name string) (models.Ledger, error) {
+	var ledger models.Ledger
+
+	err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		var err error
+		ledger, err = insertLedger(ctx, tx, uuid.New().String(), userID, name)
+		if err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "ledger",
+			entityID: ledger.ID,
+			action:   models.AuditCreate,
+			ledgerID: ledger.ID,
+			after:    ledger,
+		})
+	})
+
+	return ledger, err
+}
+
+// GetLedgers returns ledgers the user is a member of, the personal one first.
+func (db *FinanceDB) GetLedgers(ctx context.Context, userID string) ([]models.Ledger, error) {
+	const query = `
+		SELECT l.id, l.name, m.role, l.created_at, l.version
+		FROM ledgers l
+		JOIN ledger_members m ON m.ledger_id = l.id
+		WHERE m.user_id = $1
+		ORDER BY l.id = $1 DESC, l.created_at, l.id
+	`
+
+	rows, err := db.conn.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ledgers := make([]models.Ledger, 0)
+
+	for rows.Next() {
+		var ledger models.Ledger
+
+		err := rows.Scan(&ledger.ID, &ledger.Name, &ledger.Role, &ledger.CreatedAt, &ledger.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		ledgers = append(ledgers, ledger)
+	}
+
+	return ledgers, rows.Err()
+}
+
+// lockLedger reads the ledger for a change and locks it until the transaction ends.
+func lockLedger(ctx context.Context, tx pgx.Tx, ledgerID string) (models.Ledger, error) {
+	const query = `SELECT id, name, created_at, version FROM ledgers WHERE id = $1 FOR UPDATE`
+
+	var ledger models.Ledger
+	err := tx.QueryRow(ctx, query, ledgerID).Scan(&ledger.ID, &ledger.Name, &ledger.CreatedAt, &ledger.Version)
+
+	return ledger, mapError(err, "ledger")
+}
+
+func (db *FinanceDB) RenameLedger(ctx context.Context, userID, ledgerID, name string, version int64) error {
+	const query = `UPDATE ledgers SET name = $2, version = version + 1 WHERE id = $1 RETURNING version`
+
+	return pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		err := requireLedgerRole(ctx, tx, ledgerID, userID, models.LedgerOwner)
+		if err != nil {
+			return err
+		}
+
+		before, err := lockLedger(ctx, tx, ledgerID)
+		if err != nil {
+			return err
+		}
+
+		err = checkVersion(version, before.Version)
+		if err != nil {
+			return err
+		}
+
+		after := before
+		after.Name = name
+		err = tx.QueryRow(ctx, query, ledgerID, name).Scan(&after.Version)
+		if err != nil {
+			return mapError(err, "ledger")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "ledger",
+			entityID: ledgerID,
+			action:   models.AuditUpdate,
+			ledgerID: ledgerID,
+			before:   before,
+			after:    after,
+		})
+	})
+}
+
+// DeleteLedger removes the ledger with all of its transactions and categories.
+func (db *FinanceDB) DeleteLedger(ctx context.Context, userID, ledgerID string, version int64) error {
+	if ledgerID == userID {
+		return errs.Conflict("personal_ledger", "the personal ledger cannot be deleted")
+	}
+
+	return pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		err := requireLedgerRole(ctx, tx, ledgerID, userID, models.LedgerOwner)
+		if err != nil {
+			return err
+		}
+
+		ledger, err := lockLedger(ctx, tx, ledgerID)
+		if err != nil {
+			return err
+		}
+
+		err = checkVersion(version, ledger.Version)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `DELETE FROM ledgers WHERE id = $1`, ledgerID)
+		if err != nil {
+			return mapError(err, "ledger")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "ledger",
+			entityID: ledgerID,
+			action:   models.AuditDelete,
+			ledgerID: ledgerID,
+			before:   ledger,
+		})
+	})
+}
+
+func (db *FinanceDB) GetLedgerMembers(ctx context.Context, userID, ledgerID string) ([]models.LedgerMember, error) {
+	const query = `
+		SELECT m.user_id, u.username, m.role, m.created_at
+		FROM ledger_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.ledger_id = $1
+		ORDER BY m.created_at, u.username
+	`
+
+	err := requireLedgerRole(ctx, db.conn, ledgerID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.Query(ctx, query, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make([]models.LedgerMember, 0)
+
+	for rows.Next() {
+		var member models.LedgerMember
+
+		err := rows.Scan(&member.UserID, &member.UserName, &member.Role, &member.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+func (db *FinanceDB) SetLedgerMemberRole(ctx context.Context, userID, ledgerID, memberID, role string) error {
+	const query = `
+		UPDATE ledger_members m
+		SET role = $3
+		FROM (SELECT role FROM ledger_members WHERE ledger_id = $1 AND user_id = $2 FOR UPDATE) old
+		WHERE m.ledger_id = $1 AND m.user_id = $2
+		RETURNING old.role
+	`
+
+	return pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		err := requireLedgerRole(ctx, tx, ledgerID, userID, models.LedgerOwner)
+		if err != nil {
+			return err
+		}
+
+		var oldRole string
+		err = tx.QueryRow(ctx, query, ledgerID, memberID, role).Scan(&oldRole)
+		if err != nil {
+			return mapError(err, "ledger_member")
+		}
+
+		err = ensureOwnerLeft(ctx, tx, ledgerID)
+		if err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "ledger_member",
+			entityID: memberID,
+			action:   models.AuditUpdate,
+			ledgerID: ledgerID,
+			before:   map[string]string{"role": oldRole},
+			after:    map[string]string{"role": role},
+		})
+	})
+}
+
+// RemoveLedgerMember removes a member. Owners can remove anyone, other members
+// can only leave themselves. Nobody can leave their personal ledger.
+func (db *FinanceDB) RemoveLedgerMember(ctx context.Context, userID, ledgerID, memberID string) error {
+	const query = `DELETE FROM ledger_members WHERE ledger_id = $1 AND user_id = $2 RETURNING role`
+
+	if ledgerID == memberID {
+		return errs.Conflict("personal_ledger", "the owner cannot leave the personal ledger")
+	}
+
+	return pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		var roles []string
+		if memberID != userID {
+			roles = []string{models.LedgerOwner}
+		}
+
+		err := requireLedgerRole(ctx, tx, ledgerID, userID, roles...)
+		if err != nil {
+			return err
+		}
+
+		var role string
+		err = tx.QueryRow(ctx, query, ledgerID, memberID).Scan(&role)
+		if err != nil {
+			return mapError(err, "ledger_member")
+		}
+
+		err = ensureOwnerLeft(ctx, tx, ledgerID)
+		if err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "ledger_member",
+			entityID: memberID,
+			action:   models.AuditDelete,
+			ledgerID: ledgerID,
+			before:   map[string]string{"role": role},
+		})
+	})
+}
+
+// InviteToLedger invites the user with the given username or email. A repeated
+// invitation replaces the previous one.
+func (db *FinanceDB) InviteToLedger(ctx context.Context, userID, ledgerID string, input models.InviteInput) (string, error) {
+	const findUser = `
+		SELECT id FROM users
+		WHERE username = $1 OR lower(email) = lower(trim($1))
+		ORDER BY username = $1 DESC
+		LIMIT 1
+	`
+	const isMember = `SELECT EXISTS (SELECT 1 FROM ledger_members WHERE ledger_id = $1 AND user_id = $2)`
+	const invite = `
+		INSERT INTO ledger_invitations (id, ledger_id, user_id, role, invited_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (ledger_id, user_id)
+		DO UPDATE SET role = EXCLUDED.role, invited_by = EXCLUDED.invited_by, created_at = EXCLUDED.created_at
+		RETURNING id
+	`
+
+	var invitationID string
+
+	err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		err := requireLedgerRole(ctx, tx, ledgerID, userID, models.LedgerOwner)
+		if err != nil {
+			return err
+		}
+
+		var inviteeID string
+		err = tx.QueryRow(ctx, findUser, input.Login).Scan(&inviteeID)
+		if err != nil {
+			return mapError(err, "user")
+		}
+
+		var member bool
+		err = tx.QueryRow(ctx, isMember, ledgerID, inviteeID).Scan(&member)
+		if err != nil {
+			return err
+		}
+		if member {
+			return errs.Conflict("already_ledger_member", "the user is already a member of the ledger")
+		}
+
+		err = tx.QueryRow(ctx, invite, uuid.New().String(), ledgerID, inviteeID, input.Role, userID).Scan(&invitationID)
+		if err != nil {
+			return mapError(err, "ledger_invitation")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "ledger_invitation",
+			entityID: invitationID,
+			action:   models.AuditCreate,
+			ledgerID: ledgerID,
+			after:    map[string]string{"user_id": inviteeID, "role": input.Role},
+		})
+	})
+
+	return invitationID, err
+}
+
+// GetInvitations returns pending invitations of the user.
+func (db *FinanceDB) GetInvitations(ctx context.Context, userID string) ([]models.Invitation, error) {
+	const query = `
+		SELECT i.id, i.ledger_id, l.name, i.role, COALESCE(u.username, ''), i.created_at
+		FROM ledger_invitations i
+		JOIN ledgers l ON l.id = i.ledger_id
+		LEFT JOIN users u ON u.id = i.invited_by
+		WHERE i.user_id = $1
+		ORDER BY i.created_at DESC
+	`
+
+	rows, err := db.conn.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := make([]models.Invitation, 0)
+
+	for rows.Next() {
+		var invitation models.Invitation
+
+		err := rows.Scan(
+			&invitation.ID,
+			&invitation.LedgerID,
+			&invitation.LedgerName,
+			&invitation.Role,
+			&invitation.InvitedBy,
+			&invitation.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		invitations = append(invitations, invitation)
+	}
+
+	return invitations, rows.Err()
+}
+
+// AcceptInvitation makes the user a member of the ledger and returns its ID.
+func (db *FinanceDB) AcceptInvitation(ctx context.Context, userID, invitationID string) (string, error) {
+	const useInvitation = `
+		DELETE FROM ledger_invitations
+		WHERE id = $1 AND user_id = $2
+		RETURNING ledger_id, role
+	`
+	const addMember = `
+		INSERT INTO ledger_members (ledger_id, user_id, role, created_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (ledger_id, user_id) DO NOTHING
+	`
+
+	var ledgerID string
+
+	err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		var role string
+		err := tx.QueryRow(ctx, useInvitation, invitationID, userID).Scan(&ledgerID, &role)
+		if err != nil {
+			return mapError(err, "ledger_invitation")
+		}
+
+		_, err = tx.Exec(ctx, addMember, ledgerID, userID, role)
+		if err != nil {
+			return mapError(err, "ledger_member")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "ledger_member",
+			entityID: userID,
+			action:   models.AuditCreate,
+			ledgerID: ledgerID,
+			after:    map[string]string{"role": role, "invitation_id": invitationID},
+		})
+	})
+
+	return ledgerID, err
+}
+
+func (db *FinanceDB) DeclineInvitation(ctx context.Context, userID, invitationID string) error {
+	const query = `DELETE FROM ledger_invitations WHERE id = $1 AND user_id = $2 RETURNING ledger_id, role`
+
+	return pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		var ledgerID, role string
+		err := tx.QueryRow(ctx, query, invitationID, userID).Scan(&ledgerID, &role)
+		if err != nil {
+			return mapError(err, "ledger_invitation")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "ledger_invitation",
+			entityID: invitationID,
+			action:   models.AuditDelete,
+			ledgerID: ledgerID,
+			before:   map[string]string{"user_id": userID, "role": role},
+		})
+	})
+}
+
+func (db *FinanceDB) GetCategories(ctx context.Context, userID, ledgerID string) ([]models.Category, error) {
+	const query = `
+		SELECT id, ledger_id, name, version
+		FROM categories
+		WHERE ledger_id = $1 AND deleted_at IS NULL
+		ORDER BY name
+	`
+
+	err := requireLedgerRole(ctx, db.conn, ledgerID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.Query(ctx, query, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make([]models.Category, 0)
+
+	for rows.Next() {
+		var category models.Category
+
+		err := rows.Scan(&category.ID, &category.LedgerID, &category.Name, &category.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		categories = append(categories, category)
+	}
+
+	return categories, rows.Err()
+}
+
+func (db *FinanceDB) InsertCategory(ctx context.Context, userID string, category models.Category) (string, error) {
+	const query = `
+		INSERT INTO categories (id, user_id, ledger_id, name)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, version
+	`
+
+	var categoryID string
+
+	err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		err := requireLedgerRole(ctx, tx, category.LedgerID, userID, ledgerWriters...)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx, query, category.ID, userID, category.LedgerID, category.Name).
+			Scan(&categoryID, &category.Version)
+		if err != nil {
+			return mapError(err, "category")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "category",
+			entityID: categoryID,
+			action:   models.AuditCreate,
+			ledgerID: category.LedgerID,
+			after:    category,
+		})
+	})
+
+	return categoryID, err
+}
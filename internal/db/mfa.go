@@ -0,0 +1,165 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"simple-finance/internal/errs"
+	"simple-finance/internal/models"
+)
+
+// GetTOTP returns the encrypted TOTP secret of the user, whether 2FA is
+// confirmed and the last time step accepted for sign-in.
+func (db *FinanceDB) GetTOTP(ctx context.Context, userID string) ([]byte, bool, int64, error) {
+	const query = `
+		SELECT totp_secret, totp_enabled_at IS NOT NULL, totp_last_step
+		FROM users
+		WHERE id = $1
+	`
+
+	var (
+		secret   []byte
+		enabled  bool
+		lastStep int64
+	)
+	err := db.conn.QueryRow(ctx, query, userID).Scan(&secret, &enabled, &lastStep)
+
+	return secret, enabled, lastStep, mapError(err, "user")
+}
+
+// SetPendingTOTP stores a new secret that still has to be confirmed.
+func (db *FinanceDB) SetPendingTOTP(ctx context.Context, userID string, secret []byte) error {
+	const query = `
+		UPDATE users
+		SET totp_secret = $2, totp_last_step = 0
+		WHERE id = $1 AND totp_enabled_at IS NULL
+	`
+
+	tag, err := db.conn.Exec(ctx, query, userID, secret)
+	if err != nil {
+		return mapError(err, "user")
+	}
+
+	if tag.RowsAffected() == 0 {
+		return errs.Conflict("mfa_already_enabled", "two-factor authentication is already enabled")
+	}
+
+	return nil
+}
+
+// EnableTOTP confirms the pending secret and replaces recovery codes.
+func (db *FinanceDB) EnableTOTP(ctx context.Context, userID string, step int64, codeHashes []string) error {
+	const enable = `
+		UPDATE users
+		SET totp_enabled_at = NOW(), totp_last_step = $2
+		WHERE id = $1 AND totp_enabled_at IS NULL AND totp_secret IS NOT NULL
+	`
+
+	return pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, enable, userID, step)
+		if err != nil {
+			return mapError(err, "user")
+		}
+
+		if tag.RowsAffected() == 0 {
+			return errs.Conflict("mfa_not_pending", "two-factor authentication is already enabled or was not requested")
+		}
+
+		err = replaceRecoveryCodes(ctx, tx, userID, codeHashes)
+		if err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "mfa",
+			entityID: userID,
+			action:   models.AuditCreate,
+			userID:   userID,
+		})
+	})
+}
+
+// UseTOTPStep remembers the accepted time step, so one code cannot be used twice.
+func (db *FinanceDB) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	const query = `UPDATE users SET totp_last_step = $2 WHERE id = $1 AND totp_last_step < $2`
+
+	tag, err := db.conn.Exec(ctx, query, userID, step)
+	if err != nil {
+		return mapError(err, "user")
+	}
+
+	if tag.RowsAffected() == 0 {
+		return errs.Validation("mfa_code_reused", "the code has already been used")
+	}
+
+	return nil
+}
+
+func (db *FinanceDB) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	const query = `
+		UPDATE recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	tag, err := db.conn.Exec(ctx, query, userID, codeHash)
+	if err != nil {
+		return mapError(err, "recovery_code")
+	}
+
+	if tag.RowsAffected() == 0 {
+		return errs.Validation("mfa_code_invalid", "invalid two-factor code")
+	}
+
+	return nil
+}
+
+func (db *FinanceDB) DisableTOTP(ctx context.Context, userID string) error {
+	const disable = `
+		UPDATE users
+		SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0
+		WHERE id = $1
+	`
+
+	return pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, disable, userID)
+		if err != nil {
+			return mapError(err, "user")
+		}
+
+		err = replaceRecoveryCodes(ctx, tx, userID, nil)
+		if err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "mfa",
+			entityID: userID,
+			action:   models.AuditDelete,
+			userID:   userID,
+		})
+	})
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID string, codeHashes []string) error {
+	_, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, codeHash := range codeHashes {
+		_, err := tx.Exec(ctx, `INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, codeHash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IsTOTPEnabled reports whether the user has to pass the second sign-in step.
+func (db *FinanceDB) IsTOTPEnabled(ctx context.Context, userID string) (bool, error) {
+	_, enabled, _, err := db.GetTOTP(ctx, userID)
+
+	return enabled, err
+}
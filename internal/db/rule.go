@@ -0,0 +1,543 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"simple-finance/internal/errs"
+	"simple-finance/internal/models"
+	"simple-finance/internal/rules"
+)
+
+// errCategoryRequired — категорию не указал клиент и не назначило ни одно правило.
+var errCategoryRequired = errs.Validation("category_required", "category_id is required when no rule assigns a category")
+
+// maxRuleTestTransactions ограничивает пример совпадений в пробном запуске правила.
+const maxRuleTestTransactions = 100
+
+const ruleColumns = `
+	r.id, r.ledger_id, r.user_id::text, r.name, r.priority, r.enabled,
+	r.comment_contains, r.comment_regex, r.amount_min, r.amount_max, r.author_id::text, r.weekdays::int[],
+	r.category_id::text, r.tag_ids::text[], r.created_at, r.version
+`
+
+func scanRule(row pgx.Row) (models.Rule, error) {
+	var rule models.Rule
+
+	err := row.Scan(
+		&rule.ID,
+		&rule.LedgerID,
+		&rule.UserID,
+		&rule.Name,
+		&rule.Priority,
+		&rule.Enabled,
+		&rule.CommentContains,
+		&rule.CommentRegex,
+		&rule.AmountMin,
+		&rule.AmountMax,
+		&rule.AuthorID,
+		&rule.Weekdays,
+		&rule.CategoryID,
+		&rule.TagIDs,
+		&rule.CreatedAt,
+		&rule.Version,
+	)
+
+	return rule, err
+}
+
+// GetRules returns rules of the ledger in the order they are checked.
+func (db *FinanceDB) GetRules(ctx context.Context, userID, ledgerID string) ([]models.Rule, error) {
+	const query = "SELECT" + ruleColumns + `
+		FROM rules r
+		WHERE r.ledger_id = $1
+		ORDER BY r.priority, r.created_at, r.id
+	`
+
+	err := requireLedgerRole(ctx, db.conn, ledgerID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.Query(ctx, query, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]models.Rule, 0)
+
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, rule)
+	}
+
+	return list, rows.Err()
+}
+
+// ledgerEngine loads enabled rules of the ledger for matching. A category in
+// the trash is not assigned, such rules only add their tags.
+func ledgerEngine(ctx context.Context, tx pgx.Tx, ledgerID string) (*rules.Engine, error) {
+	const query = `
+		SELECT
+			r.id, r.ledger_id, r.user_id::text, r.name, r.priority, r.enabled,
+			r.comment_contains, r.comment_regex, r.amount_min, r.amount_max, r.author_id::text, r.weekdays::int[],
+			CASE WHEN c.deleted_at IS NULL THEN r.category_id::text END, r.tag_ids::text[], r.created_at, r.version
+		FROM rules r
+		LEFT JOIN categories c ON c.id = r.category_id
+		WHERE r.ledger_id = $1 AND r.enabled
+		ORDER BY r.priority, r.created_at, r.id
+	`
+
+	rows, err := tx.Query(ctx, query, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]models.Rule, 0)
+
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules.New(list)
+}
+
+// assignByRules applies the first matching rule to a new transaction. The
+// category the client chose wins over the rule's one. It returns tags to attach.
+func assignByRules(engine *rules.Engine, transaction *models.Transaction) []string {
+	rule, ok := engine.Match(*transaction)
+	if !ok {
+		return nil
+	}
+
+	if transaction.CategoryID == "" && rule.CategoryID != nil {
+		transaction.CategoryID = *rule.CategoryID
+	}
+
+	return rule.TagIDs
+}
+
+// attachRuleTags attaches tags by pairs of transaction and tag IDs. Tags moved
+// to the trash since the rule was saved are skipped. It returns IDs of
+// transactions that got new tags.
+func attachRuleTags(ctx context.Context, tx pgx.Tx, transactionIDs, tagIDs []string) (map[string]bool, error) {
+	const query = `
+		INSERT INTO transaction_tags (transaction_id, tag_id)
+		SELECT v.transaction_id::uuid, g.id
+		FROM unnest($1::text[], $2::text[]) AS v(transaction_id, tag_id)
+		JOIN tags g ON g.id = v.tag_id::uuid AND g.deleted_at IS NULL
+		ON CONFLICT DO NOTHING
+		RETURNING transaction_id
+	`
+
+	tagged := make(map[string]bool)
+
+	if len(transactionIDs) == 0 {
+		return tagged, nil
+	}
+
+	rows, err := tx.Query(ctx, query, transactionIDs, tagIDs)
+	if err != nil {
+		return nil, mapError(err, "tag")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transactionID string
+
+		err := rows.Scan(&transactionID)
+		if err != nil {
+			return nil, err
+		}
+
+		tagged[transactionID] = true
+	}
+
+	return tagged, rows.Err()
+}
+
+// checkRuleRefs checks what the rule points to: the category has to be a live
+// category of the ledger, the author a member of it and tags the user's own.
+func checkRuleRefs(ctx context.Context, tx pgx.Tx, userID string, rule models.Rule) error {
+	if rule.CategoryID != nil {
+		err := requireLedgerCategory(ctx, tx, rule.LedgerID, *rule.CategoryID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if rule.AuthorID != nil {
+		err := requireLedgerRole(ctx, tx, rule.LedgerID, *rule.AuthorID)
+		if errs.IsKind(err, errs.ErrNotFound) {
+			return errs.Validation("author_not_found", "author is not a member of the ledger")
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return requireOwnTags(ctx, tx, userID, uniqueIDs(rule.TagIDs))
+}
+
+// InsertRule adds a rule to the ledger. Requires the owner or editor role.
+func (db *FinanceDB) InsertRule(ctx context.Context, userID string, rule models.Rule) (models.Rule, error) {
+	const query = `
+		INSERT INTO rules (
+			id, ledger_id, user_id, name, priority, enabled,
+			comment_contains, comment_regex, amount_min, amount_max, author_id, weekdays,
+			category_id, tag_ids, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12::smallint[], $13, $14::text[]::uuid[], NOW())
+		RETURNING created_at, version
+	`
+
+	rule.UserID = &userID
+
+	err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		err := requireLedgerRole(ctx, tx, rule.LedgerID, userID, ledgerWriters...)
+		if err != nil {
+			return err
+		}
+
+		err = checkRuleRefs(ctx, tx, userID, rule)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx, query,
+			rule.ID,
+			rule.LedgerID,
+			userID,
+			rule.Name,
+			rule.Priority,
+			rule.Enabled,
+			rule.CommentContains,
+			rule.CommentRegex,
+			rule.AmountMin,
+			rule.AmountMax,
+			rule.AuthorID,
+			rule.Weekdays,
+			rule.CategoryID,
+			rule.TagIDs,
+		).Scan(&rule.CreatedAt, &rule.Version)
+		if err != nil {
+			return mapError(err, "rule")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "rule",
+			entityID: rule.ID,
+			action:   models.AuditCreate,
+			ledgerID: rule.LedgerID,
+			after:    rule,
+		})
+	})
+
+	return rule, err
+}
+
+func lockRule(ctx context.Context, tx pgx.Tx, ledgerID, ruleID string) (models.Rule, error) {
+	const query = "SELECT" + ruleColumns + `
+		FROM rules r
+		WHERE r.id = $1 AND r.ledger_id = $2
+		FOR UPDATE
+	`
+
+	rule, err := scanRule(tx.QueryRow(ctx, query, ruleID, ledgerID))
+
+	return rule, mapError(err, "rule")
+}
+
+// UpdateRule replaces the rule definition if version matches the current one.
+// The user becomes the one whose tags the rule attaches.
+func (db *FinanceDB) UpdateRule(ctx context.Context, userID string, rule models.Rule, version int64) (models.Rule, error) {
+	const query = `
+		UPDATE rules
+		SET user_id = $3, name = $4, priority = $5, enabled = $6,
+		    comment_contains = $7, comment_regex = $8, amount_min = $9, amount_max = $10, author_id = $11,
+		    weekdays = $12::smallint[], category_id = $13, tag_ids = $14::text[]::uuid[],
+		    version = version + 1
+		WHERE id = $1 AND ledger_id = $2
+		RETURNING created_at, version
+	`
+
+	rule.UserID = &userID
+
+	err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		err := requireLedgerRole(ctx, tx, rule.LedgerID, userID, ledgerWriters...)
+		if err != nil {
+			return err
+		}
+
+		current, err := lockRule(ctx, tx, rule.LedgerID, rule.ID)
+		if err != nil {
+			return err
+		}
+
+		err = checkVersion(version, current.Version)
+		if err != nil {
+			return err
+		}
+
+		err = checkRuleRefs(ctx, tx, userID, rule)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx, query,
+			rule.ID,
+			rule.LedgerID,
+			userID,
+			rule.Name,
+			rule.Priority,
+			rule.Enabled,
+			rule.CommentContains,
+			rule.CommentRegex,
+			rule.AmountMin,
+			rule.AmountMax,
+			rule.AuthorID,
+			rule.Weekdays,
+			rule.CategoryID,
+			rule.TagIDs,
+		).Scan(&rule.CreatedAt, &rule.Version)
+		if err != nil {
+			return mapError(err, "rule")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "rule",
+			entityID: rule.ID,
+			action:   models.AuditUpdate,
+			ledgerID: rule.LedgerID,
+			before:   current,
+			after:    rule,
+		})
+	})
+
+	return rule, err
+}
+
+// DeleteRule removes the rule if version matches the current one. Rules are
+// settings rather than data, so they are deleted without the trash.
+func (db *FinanceDB) DeleteRule(ctx context.Context, userID, ledgerID, ruleID string, version int64) error {
+	const query = `DELETE FROM rules WHERE id = $1 AND ledger_id = $2`
+
+	return pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		err := requireLedgerRole(ctx, tx, ledgerID, userID, ledgerWriters...)
+		if err != nil {
+			return err
+		}
+
+		rule, err := lockRule(ctx, tx, ledgerID, ruleID)
+		if err != nil {
+			return err
+		}
+
+		err = checkVersion(version, rule.Version)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, query, ruleID, ledgerID)
+		if err != nil {
+			return mapError(err, "rule")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "rule",
+			entityID: rule.ID,
+			action:   models.AuditDelete,
+			ledgerID: rule.LedgerID,
+			before:   rule,
+		})
+	})
+}
+
+// TestRule runs the rule against live transactions of the ledger without
+// changing them. The rule does not have to be saved.
+func (db *FinanceDB) TestRule(ctx context.Context, userID string, rule models.Rule) (models.RuleTestResult, error) {
+	const query = "SELECT" + transactionColumns + `
+		FROM transactions t
+		WHERE t.ledger_id = $1 AND t.deleted_at IS NULL
+		ORDER BY t.date DESC, t.created_at DESC, t.id
+	`
+
+	result := models.RuleTestResult{Transactions: make([]models.Transaction, 0)}
+
+	err := requireLedgerRole(ctx, db.conn, rule.LedgerID, userID)
+	if err != nil {
+		return result, err
+	}
+
+	engine, err := rules.New([]models.Rule{rule})
+	if err != nil {
+		return result, err
+	}
+
+	rows, err := db.conn.Query(ctx, query, rule.LedgerID)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return result, err
+		}
+
+		if _, ok := engine.Match(transaction); !ok {
+			continue
+		}
+
+		result.Matched++
+		if len(result.Transactions) < maxRuleTestTransactions {
+			result.Transactions = append(result.Transactions, transaction)
+		}
+	}
+
+	return result, rows.Err()
+}
+
+// ApplyRules re-applies enabled rules of the ledger to its live transactions.
+// Unlike on creation, the rule's category replaces the current one. Requires
+// the owner or editor role.
+func (db *FinanceDB) ApplyRules(ctx context.Context, userID, ledgerID string, input models.ApplyRulesInput) (models.RuleApplyResult, error) {
+	const query = `
+		UPDATE transactions AS t
+		SET category_id = COALESCE(NULLIF(v.category_id, '')::uuid, t.category_id), version = t.version + 1
+		FROM unnest($1::text[], $2::text[]) AS v(id, category_id)
+		WHERE t.id = v.id::uuid
+		RETURNING` + transactionColumns
+
+	result := models.RuleApplyResult{Items: make([]models.RuleApplyItem, 0)}
+
+	err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		err := requireLedgerRole(ctx, tx, ledgerID, userID, ledgerWriters...)
+		if err != nil {
+			return err
+		}
+
+		engine, err := ledgerEngine(ctx, tx, ledgerID)
+		if err != nil {
+			return err
+		}
+
+		locked, err := lockLedgerTransactions(ctx, tx, ledgerID, input.DateFrom, input.DateTo)
+		if err != nil {
+			return err
+		}
+		result.Checked = len(locked)
+
+		matched := make(map[string]models.Rule)
+		categories := make(map[string]string)
+		var tagTransactionIDs, tagIDs []string
+
+		for _, transaction := range locked {
+			rule, ok := engine.Match(transaction)
+			if !ok {
+				continue
+			}
+			matched[transaction.ID] = rule
+
+			if rule.CategoryID != nil && *rule.CategoryID != transaction.CategoryID {
+				categories[transaction.ID] = *rule.CategoryID
+			}
+
+			for _, tagID := range rule.TagIDs {
+				tagTransactionIDs = append(tagTransactionIDs, transaction.ID)
+				tagIDs = append(tagIDs, tagID)
+			}
+		}
+
+		tagged, err := attachRuleTags(ctx, tx, tagTransactionIDs, tagIDs)
+		if err != nil {
+			return err
+		}
+
+		var changed []models.Transaction
+		var ids, categoryIDs []string
+
+		for _, transaction := range locked {
+			_, recategorised := categories[transaction.ID]
+			if !recategorised && !tagged[transaction.ID] {
+				continue
+			}
+
+			changed = append(changed, transaction)
+			ids = append(ids, transaction.ID)
+			categoryIDs = append(categoryIDs, categories[transaction.ID])
+			result.Items = append(result.Items, models.RuleApplyItem{
+				TransactionID: transaction.ID,
+				RuleID:        matched[transaction.ID].ID,
+			})
+		}
+		result.Changed = len(changed)
+
+		if len(changed) == 0 {
+			return nil
+		}
+
+		return applyBatch(ctx, tx, models.AuditUpdate, changed, query, ids, categoryIDs)
+	})
+
+	return result, err
+}
+
+// lockLedgerTransactions locks live transactions of the ledger within the
+// optional date range in ID order. Like batch operations, it refuses to lock
+// more than maxBatchSize of them.
+func lockLedgerTransactions(ctx context.Context, tx pgx.Tx, ledgerID string, from, to *time.Time) ([]models.Transaction, error) {
+	const query = "SELECT" + transactionColumns + `
+		FROM transactions t
+		WHERE t.ledger_id = $1 AND t.deleted_at IS NULL
+		  AND ($2::date IS NULL OR t.date >= $2::date)
+		  AND ($3::date IS NULL OR t.date <= $3::date)
+		ORDER BY t.id
+		LIMIT $4
+		FOR UPDATE
+	`
+
+	rows, err := tx.Query(ctx, query, ledgerID, from, to, maxBatchSize+1)
+	if err != nil {
+		return nil, mapError(err, "transaction")
+	}
+	defer rows.Close()
+
+	transactions := make([]models.Transaction, 0)
+
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		transactions = append(transactions, transaction)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(transactions) > maxBatchSize {
+		return nil, errs.Validation("batch_too_large",
+			fmt.Sprintf("more than %d transactions in the date range, narrow it down", maxBatchSize))
+	}
+
+	return transactions, nil
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+
+	"simple-finance/internal/models"
+)
+
+// SearchTransactions finds live transactions of the user's ledgers by comment,
+// category and the user's tag names. Full-text matches in both configurations
+// are ranked first, trigram similarity catches typos and partial words.
+func (db *FinanceDB) SearchTransactions(ctx context.Context, userID string, filter models.SearchFilter) ([]models.SearchResult, error) {
+	// Комментарий экранируется до ts_headline, чтобы в сниппете был только наш <mark>.
+	const query = `
+		WITH q AS (
+			SELECT websearch_to_tsquery('russian', $2::text) || websearch_to_tsquery('english', $2::text) AS query
+		)
+		SELECT` + transactionColumns + `,
+			c.name,
+			COALESCE(tg.names, '{}'),
+			ts_rank_cd(t.search_vector || setweight(cv.vector, 'B') || setweight(tv.vector, 'C'), q.query)
+				+ GREATEST(word_similarity($2::text, t.comment), word_similarity($2::text, c.name)) / 2 AS rank,
+			ts_headline(
+				'russian',
+				replace(replace(replace(t.comment, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+				q.query,
+				'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MinWords=5, MaxWords=20'
+			)
+		FROM transactions t
+		JOIN ledger_members m ON m.ledger_id = t.ledger_id AND m.user_id = $1
+		JOIN categories c ON c.id = t.category_id
+		LEFT JOIN LATERAL (
+			SELECT array_agg(g.name ORDER BY g.name) AS names
+			FROM transaction_tags tt
+			JOIN tags g ON g.id = tt.tag_id
+			WHERE tt.transaction_id = t.id AND g.user_id = $1 AND g.deleted_at IS NULL
+		) tg ON TRUE
+		CROSS JOIN q
+		CROSS JOIN LATERAL (
+			SELECT to_tsvector('russian', c.name) || to_tsvector('english', c.name) AS vector
+		) cv
+		CROSS JOIN LATERAL (
+			SELECT to_tsvector('russian', COALESCE(array_to_string(tg.names, ' '), ''))
+				|| to_tsvector('english', COALESCE(array_to_string(tg.names, ' '), '')) AS vector
+		) tv
+		WHERE t.deleted_at IS NULL
+		  AND ($3::uuid IS NULL OR t.ledger_id = $3::uuid)
+		  AND ($4::date IS NULL OR t.date >= $4::date)
+		  AND ($5::date IS NULL OR t.date <= $5::date)
+		  AND (
+			t.search_vector @@ q.query
+			OR cv.vector @@ q.query
+			OR tv.vector @@ q.query
+			OR $2::text <% t.comment
+			OR $2::text <% c.name
+			OR EXISTS (SELECT 1 FROM unnest(tg.names) AS n(name) WHERE $2::text <% n.name)
+		  )
+		ORDER BY rank DESC, t.date DESC, t.id
+		LIMIT $6 OFFSET $7
+	`
+
+	if filter.LedgerID != "" {
+		err := requireLedgerRole(ctx, db.conn, filter.LedgerID, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := db.conn.Query(ctx, query,
+		userID,
+		filter.Query,
+		nullable(filter.LedgerID),
+		filter.DateFrom,
+		filter.DateTo,
+		filter.Limit,
+		filter.Offset,
+	)
+	if err != nil {
+		return nil, mapError(err, "transaction")
+	}
+	defer rows.Close()
+
+	results := make([]models.SearchResult, 0)
+
+	for rows.Next() {
+		var result models.SearchResult
+
+		dest := append(transactionFields(&result.Transaction),
+			&result.CategoryName,
+			&result.Tags,
+			&result.Rank,
+			&result.Snippet,
+		)
+
+		err := rows.Scan(dest...)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
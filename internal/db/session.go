@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"simple-finance/internal/errs"
+	"simple-finance/internal/models"
+)
+
+func (db *FinanceDB) InsertSession(ctx context.Context, userID string, session models.Session, expiresAt time.Time) error {
+	const query = `
+		INSERT INTO sessions (id, user_id, user_agent, ip, created_at, last_seen_at, expires_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW(), $5)
+	`
+
+	_, err := db.conn.Exec(ctx, query, session.ID, userID, session.UserAgent, session.IP, expiresAt)
+	return mapError(err, "session")
+}
+
+// GetSessions returns active sessions of the user, most recently used first.
+func (db *FinanceDB) GetSessions(ctx context.Context, userID string) ([]models.Session, error) {
+	const query = `
+		SELECT id, user_agent, ip, created_at, last_seen_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_seen_at DESC
+	`
+
+	rows, err := db.conn.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]models.Session, 0)
+
+	for rows.Next() {
+		var session models.Session
+
+		err := rows.Scan(
+			&session.ID,
+			&session.UserAgent,
+			&session.IP,
+			&session.CreatedAt,
+			&session.LastSeenAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// TouchSession checks that the session is active and records that it was seen.
+// last_seen_at is updated at most once per lastUsedPrecision.
+func (db *FinanceDB) TouchSession(ctx context.Context, sessionID string) error {
+	const query = `
+		SELECT last_seen_at
+		FROM sessions
+		WHERE id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+	`
+	const touch = "UPDATE sessions SET last_seen_at = NOW() WHERE id = $1"
+
+	var lastSeenAt time.Time
+	err := db.conn.QueryRow(ctx, query, sessionID).Scan(&lastSeenAt)
+	if err != nil {
+		return mapError(err, "session")
+	}
+
+	if time.Since(lastSeenAt) > lastUsedPrecision {
+		_, err = db.conn.Exec(ctx, touch, sessionID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExtendSession moves the expiry of an active session, used on token refresh.
+func (db *FinanceDB) ExtendSession(ctx context.Context, sessionID string, expiresAt time.Time) error {
+	const query = `
+		UPDATE sessions
+		SET expires_at = $2, last_seen_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+	`
+
+	tag, err := db.conn.Exec(ctx, query, sessionID, expiresAt)
+	if err != nil {
+		return mapError(err, "session")
+	}
+
+	if tag.RowsAffected() == 0 {
+		return errs.NotFound("session_not_found", "session not found")
+	}
+
+	return nil
+}
+
+func (db *FinanceDB) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	const query = `
+		UPDATE sessions
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND id = $2 AND revoked_at IS NULL AND expires_at > NOW()
+	`
+
+	tag, err := db.conn.Exec(ctx, query, userID, sessionID)
+	if err != nil {
+		return mapError(err, "session")
+	}
+
+	if tag.RowsAffected() == 0 {
+		return errs.NotFound("session_not_found", "session not found")
+	}
+
+	return nil
+}
+
+func (db *FinanceDB) RevokeSessions(ctx context.Context, userID string) error {
+	const query = "UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL"
+
+	_, err := db.conn.Exec(ctx, query, userID)
+	return mapError(err, "session")
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+
+	"simple-finance/internal/models"
+	"simple-finance/internal/suggest"
+)
+
+const (
+	// maxTrainingExamples — модель учится на последних операциях, привычки меняются.
+	maxTrainingExamples = 5000
+
+	// При импорте подсказка заменяет категорию, только если модель уверена
+	// и ей было на чём учиться.
+	importMinProbability = 0.6
+	importMinExamples    = 20
+)
+
+// trainSuggester trains a model on live transactions the user created in the
+// ledger whose categories are not in the trash. It also returns category names.
+func trainSuggester(ctx context.Context, q querier, userID, ledgerID string) (*suggest.Model, map[string]string, error) {
+	const query = `
+		SELECT t.comment, t.amount, t.category_id, c.name
+		FROM transactions t
+		JOIN categories c ON c.id = t.category_id AND c.deleted_at IS NULL
+		WHERE t.ledger_id = $1 AND t.user_id = $2 AND t.deleted_at IS NULL
+		ORDER BY t.date DESC, t.created_at DESC
+		LIMIT $3
+	`
+
+	rows, err := q.Query(ctx, query, ledgerID, userID, maxTrainingExamples)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	examples := make([]suggest.Example, 0)
+	names := make(map[string]string)
+
+	for rows.Next() {
+		var example suggest.Example
+		var name string
+
+		err := rows.Scan(&example.Comment, &example.Amount, &example.CategoryID, &name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		examples = append(examples, example)
+		names[example.CategoryID] = name
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return suggest.Train(examples), names, nil
+}
+
+// SuggestCategories ranks categories of the ledger for a new transaction by
+// the user's own history in it.
+func (db *FinanceDB) SuggestCategories(
+	ctx context.Context,
+	userID, ledgerID, comment string,
+	amount float64,
+	limit int,
+) ([]models.CategorySuggestion, error) {
+	err := requireLedgerRole(ctx, db.conn, ledgerID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	model, names, err := trainSuggester(ctx, db.conn, userID, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+
+	predicted := model.Predict(comment, amount, limit)
+	suggestions := make([]models.CategorySuggestion, 0, len(predicted))
+
+	for _, p := range predicted {
+		suggestions = append(suggestions, models.CategorySuggestion{
+			CategoryID:   p.CategoryID,
+			CategoryName: names[p.CategoryID],
+			Probability:  p.Probability,
+		})
+	}
+
+	return suggestions, nil
+}
+
+// suggestForImport returns the category the model is confident about.
+func suggestForImport(model *suggest.Model, transaction models.Transaction) (string, bool) {
+	if model.Examples() < importMinExamples {
+		return "", false
+	}
+
+	predicted := model.Predict(transaction.Comment, transaction.Amount, 1)
+	if len(predicted) == 0 || predicted[0].Probability < importMinProbability {
+		return "", false
+	}
+
+	return predicted[0].CategoryID, true
+}
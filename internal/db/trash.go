@@ -0,0 +1,284 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"simple-finance/internal/errs"
+	"simple-finance/internal/models"
+)
+
+// RestoreTransaction returns the transaction from the trash. Its category has
+// to be restored first if it was deleted as well.
+func (db *FinanceDB) RestoreTransaction(ctx context.Context, userID, transactionID string) error {
+	const categoryDeleted = `SELECT deleted_at IS NOT NULL FROM categories WHERE id = $1`
+	const restore = `
+		UPDATE transactions AS t
+		SET deleted_at = NULL, version = version + 1
+		WHERE t.id = $1
+		RETURNING` + transactionColumns
+
+	return pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		trashed, err := lockTransaction(ctx, tx, userID, transactionID, true)
+		if err != nil {
+			return err
+		}
+
+		err = requireLedgerRole(ctx, tx, trashed.LedgerID, userID, ledgerWriters...)
+		if err != nil {
+			return err
+		}
+
+		var deleted bool
+		err = tx.QueryRow(ctx, categoryDeleted, trashed.CategoryID).Scan(&deleted)
+		if err != nil {
+			return mapError(err, "category")
+		}
+		if deleted {
+			return errs.Conflict("category_deleted", "restore the category of the transaction first")
+		}
+
+		restored, err := scanTransaction(tx.QueryRow(ctx, restore, transactionID))
+		if err != nil {
+			return mapError(err, "transaction")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "transaction",
+			entityID: restored.ID,
+			action:   models.AuditRestore,
+			ledgerID: restored.LedgerID,
+			before:   trashed,
+			after:    restored,
+		})
+	})
+}
+
+// lockCategory reads the category of the ledger for a change and locks it
+// until the transaction ends. deleted selects between live categories and the trash.
+func lockCategory(ctx context.Context, tx pgx.Tx, ledgerID, categoryID string, deleted bool) (models.Category, error) {
+	const query = `
+		SELECT id, ledger_id, name, version, deleted_at
+		FROM categories
+		WHERE id = $1 AND ledger_id = $2 AND (deleted_at IS NOT NULL) = $3
+		FOR UPDATE
+	`
+
+	var category models.Category
+	err := tx.QueryRow(ctx, query, categoryID, ledgerID, deleted).
+		Scan(&category.ID, &category.LedgerID, &category.Name, &category.Version, &category.DeletedAt)
+
+	return category, mapError(err, "category")
+}
+
+// DeleteCategory moves the category to the trash. A category that still has
+// transactions cannot be deleted, so no transaction is left without one.
+func (db *FinanceDB) DeleteCategory(ctx context.Context, userID, ledgerID, categoryID string, version int64) error {
+	const inUse = `
+		SELECT EXISTS (SELECT 1 FROM transactions WHERE category_id = $1 AND deleted_at IS NULL)
+	`
+	const query = `
+		UPDATE categories
+		SET deleted_at = NOW(), version = version + 1
+		WHERE id = $1
+		RETURNING id, ledger_id, name, version, deleted_at
+	`
+
+	return pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		err := requireLedgerRole(ctx, tx, ledgerID, userID, ledgerWriters...)
+		if err != nil {
+			return err
+		}
+
+		current, err := lockCategory(ctx, tx, ledgerID, categoryID, false)
+		if err != nil {
+			return err
+		}
+
+		err = checkVersion(version, current.Version)
+		if err != nil {
+			return err
+		}
+
+		var used bool
+		err = tx.QueryRow(ctx, inUse, categoryID).Scan(&used)
+		if err != nil {
+			return mapError(err, "category")
+		}
+		if used {
+			return errs.Conflict("category_in_use", "the category still has transactions")
+		}
+
+		var deleted models.Category
+		err = tx.QueryRow(ctx, query, categoryID).
+			Scan(&deleted.ID, &deleted.LedgerID, &deleted.Name, &deleted.Version, &deleted.DeletedAt)
+		if err != nil {
+			return mapError(err, "category")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "category",
+			entityID: deleted.ID,
+			action:   models.AuditDelete,
+			ledgerID: deleted.LedgerID,
+			before:   current,
+			after:    deleted,
+		})
+	})
+}
+
+func (db *FinanceDB) RestoreCategory(ctx context.Context, userID, ledgerID, categoryID string) error {
+	const query = `
+		UPDATE categories
+		SET deleted_at = NULL, version = version + 1
+		WHERE id = $1
+		RETURNING id, ledger_id, name, version
+	`
+
+	return pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		err := requireLedgerRole(ctx, tx, ledgerID, userID, ledgerWriters...)
+		if err != nil {
+			return err
+		}
+
+		trashed, err := lockCategory(ctx, tx, ledgerID, categoryID, true)
+		if err != nil {
+			return err
+		}
+
+		var restored models.Category
+		err = tx.QueryRow(ctx, query, categoryID).
+			Scan(&restored.ID, &restored.LedgerID, &restored.Name, &restored.Version)
+		if err != nil {
+			return mapError(err, "category")
+		}
+
+		return writeAudit(ctx, tx, auditRecord{
+			entity:   "category",
+			entityID: restored.ID,
+			action:   models.AuditRestore,
+			ledgerID: restored.LedgerID,
+			before:   trashed,
+			after:    restored,
+		})
+	})
+}
+
+// GetTrash returns deleted transactions, categories and goal contributions of the ledger.
+func (db *FinanceDB) GetTrash(ctx context.Context, userID, ledgerID string) (models.Trash, error) {
+	const transactionsQuery = "SELECT" + transactionColumns + `
+		FROM transactions t
+		WHERE t.ledger_id = $1 AND t.deleted_at IS NOT NULL
+		ORDER BY t.deleted_at DESC, t.id
+	`
+	const categoriesQuery = `
+		SELECT id, ledger_id, name, version, deleted_at
+		FROM categories
+		WHERE ledger_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id
+	`
+	const contributionsQuery = "SELECT" + contributionColumns + `
+		FROM incomes i
+		JOIN goals g ON g.id = i.goal_id
+		WHERE g.ledger_id = $1 AND i.deleted_at IS NOT NULL
+		ORDER BY i.deleted_at DESC, i.id
+	`
+
+	err := requireLedgerRole(ctx, db.conn, ledgerID, userID)
+	if err != nil {
+		return models.Trash{}, err
+	}
+
+	trash := models.Trash{
+		Transactions:  make([]models.Transaction, 0),
+		Categories:    make([]models.Category, 0),
+		Contributions: make([]models.Contribution, 0),
+	}
+
+	rows, err := db.conn.Query(ctx, transactionsQuery, ledgerID)
+	if err != nil {
+		return models.Trash{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return models.Trash{}, err
+		}
+
+		trash.Transactions = append(trash.Transactions, transaction)
+	}
+	if err := rows.Err(); err != nil {
+		return models.Trash{}, err
+	}
+
+	rows, err = db.conn.Query(ctx, categoriesQuery, ledgerID)
+	if err != nil {
+		return models.Trash{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var category models.Category
+
+		err := rows.Scan(&category.ID, &category.LedgerID, &category.Name, &category.Version, &category.DeletedAt)
+		if err != nil {
+			return models.Trash{}, err
+		}
+
+		trash.Categories = append(trash.Categories, category)
+	}
+	if err := rows.Err(); err != nil {
+		return models.Trash{}, err
+	}
+
+	rows, err = db.conn.Query(ctx, contributionsQuery, ledgerID)
+	if err != nil {
+		return models.Trash{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		contribution, err := scanContribution(rows)
+		if err != nil {
+			return models.Trash{}, err
+		}
+
+		trash.Contributions = append(trash.Contributions, contribution)
+	}
+
+	return trash, rows.Err()
+}
+
+// PurgeTrash permanently removes rows that have been in the trash longer than
+// retention and returns how many were removed. Categories go last and only
+// when no transaction refers to them anymore.
+func (db *FinanceDB) PurgeTrash(ctx context.Context, retention time.Duration) (int64, error) {
+	queries := []string{
+		`DELETE FROM transactions WHERE deleted_at < NOW() - $1::interval`,
+		`DELETE FROM incomes WHERE deleted_at < NOW() - $1::interval`,
+		`DELETE FROM tags WHERE deleted_at < NOW() - $1::interval`,
+		`DELETE FROM categories c
+		WHERE c.deleted_at < NOW() - $1::interval
+		  AND NOT EXISTS (SELECT 1 FROM transactions t WHERE t.category_id = c.id)`,
+	}
+
+	var purged int64
+
+	err := pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		for _, query := range queries {
+			tag, err := tx.Exec(ctx, query, retention)
+			if err != nil {
+				return err
+			}
+
+			purged += tag.RowsAffected()
+		}
+
+		return nil
+	})
+
+	return purged, err
+}
@@ -0,0 +1,16 @@
+package db
+
+import "simple-finance/internal/errs"
+
+// AnyVersion skips the version check, as If-Match: * does.
+const AnyVersion int64 = -1
+
+// checkVersion compares the version the client has seen with the current one
+// read under a row lock, so a concurrent change cannot slip in between.
+func checkVersion(expected, actual int64) error {
+	if expected != AnyVersion && expected != actual {
+		return errs.PreconditionFailed("version_mismatch", "the resource has been changed, reload it and try again")
+	}
+
+	return nil
+}
@@ -1,5 +1,85 @@
 package errs
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
-var ErrInvalidPassword = errors.New("invalid password")
+var (
+	ErrInvalidPassword = errors.New("invalid password")
+	ErrInvalidToken    = errors.New("invalid token")
+)
+
+// Виды доменных ошибок. Конкретная ошибка оборачивает один из них,
+// поэтому проверять её следует через errors.Is.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+	ErrForbidden  = errors.New("forbidden")
+	ErrTooMany    = errors.New("too many requests")
+	// ErrPrecondition — ресурс изменился с тех пор, как клиент его прочитал.
+	ErrPrecondition = errors.New("precondition failed")
+)
+
+// Error is a domain error with a stable machine-readable code that is safe
+// to show to API clients.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+	Fields  []FieldError
+	// RetryAfter подсказывает клиенту, когда можно повторить запрос.
+	RetryAfter time.Duration
+}
+
+// FieldError describes a single invalid field of a request body.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+func NotFound(code, message string) error {
+	return &Error{Kind: ErrNotFound, Code: code, Message: message}
+}
+
+func Conflict(code, message string) error {
+	return &Error{Kind: ErrConflict, Code: code, Message: message}
+}
+
+func Validation(code, message string) error {
+	return &Error{Kind: ErrValidation, Code: code, Message: message}
+}
+
+func InvalidFields(message string, fields []FieldError) error {
+	return &Error{Kind: ErrValidation, Code: "validation_failed", Message: message, Fields: fields}
+}
+
+func Forbidden(code, message string) error {
+	return &Error{Kind: ErrForbidden, Code: code, Message: message}
+}
+
+func TooManyRequests(code, message string, retryAfter time.Duration) error {
+	return &Error{Kind: ErrTooMany, Code: code, Message: message, RetryAfter: retryAfter}
+}
+
+func PreconditionFailed(code, message string) error {
+	return &Error{Kind: ErrPrecondition, Code: code, Message: message}
+}
+
+// IsKind reports whether err is a domain error of the given kind.
+func IsKind(err error, kind error) bool {
+	var domainErr *Error
+	return errors.As(err, &domainErr) && errors.Is(domainErr.Kind, kind)
+}
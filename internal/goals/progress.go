@@ -0,0 +1,90 @@
+// Package goals computes progress of savings goals.
+package goals
+
+import (
+	"math"
+	"time"
+
+	"simple-finance/internal/errs"
+	"simple-finance/internal/models"
+)
+
+const (
+	// RecentDays — за сколько последних дней берётся темп взносов для прогноза.
+	RecentDays = 90
+
+	daysPerMonth = 365.25 / 12
+	// Прогноз дальше ста лет бесполезен и переполняет даты.
+	maxProjectionDays = 100 * 365
+)
+
+// Compute fills progress of the goal from the sum of all contributions and of
+// contributions over the last RecentDays days as of today.
+func Compute(goal models.Goal, saved, recent float64, today time.Time) models.GoalProgress {
+	today = truncateDay(today)
+
+	progress := models.GoalProgress{
+		Saved:       saved,
+		Remaining:   math.Max(goal.TargetAmount-saved, 0),
+		Percent:     math.Min(saved/goal.TargetAmount*100, 100),
+		MonthlyRate: recent / RecentDays * daysPerMonth,
+	}
+	progress.Completed = progress.Remaining == 0
+
+	if progress.Completed {
+		return progress
+	}
+
+	if goal.TargetDate != nil {
+		// Если срок прошёл, недостающее нужно внести за один месяц.
+		months := math.Max(math.Ceil(truncateDay(*goal.TargetDate).Sub(today).Hours()/24/daysPerMonth), 1)
+		required := progress.Remaining / months
+		progress.RequiredMonthly = &required
+	}
+
+	if recent > 0 {
+		days := math.Ceil(progress.Remaining / (recent / RecentDays))
+		if days <= maxProjectionDays {
+			projected := today.AddDate(0, 0, int(days))
+			progress.ProjectedDate = &projected
+		}
+	}
+
+	return progress
+}
+
+// FromInput builds a goal from the request. Without start_date in the
+// request StartDate stays zero, Resolve fills it in.
+func FromInput(input models.GoalInput) models.Goal {
+	goal := models.Goal{
+		Name:         input.Name,
+		TargetAmount: input.TargetAmount,
+		TargetDate:   input.TargetDate,
+		CategoryID:   input.CategoryID,
+	}
+
+	if input.StartDate != nil {
+		goal.StartDate = truncateDay(*input.StartDate)
+	}
+
+	return goal
+}
+
+// Resolve sets the start date of a goal built by FromInput to defaultStart
+// when the request had none: today for a new goal, the stored one on update.
+// The target date is checked against the start date that will be stored.
+func Resolve(goal models.Goal, defaultStart time.Time) (models.Goal, error) {
+	if goal.StartDate.IsZero() {
+		goal.StartDate = truncateDay(defaultStart)
+	}
+
+	if goal.TargetDate != nil && !truncateDay(*goal.TargetDate).After(goal.StartDate) {
+		return models.Goal{}, errs.Validation("goal_invalid_target_date", "target_date must be after start_date")
+	}
+
+	return goal, nil
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
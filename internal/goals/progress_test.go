@@ -0,0 +1,181 @@
+package goals
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"simple-finance/internal/errs"
+	"simple-finance/internal/models"
+)
+
+var today = time.Date(2024, time.March, 15, 18, 30, 0, 0, time.UTC)
+
+func date(year int, month time.Month, day int) *time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name          string
+		target        float64
+		targetDate    *time.Time
+		saved, recent float64
+
+		remaining float64
+		percent   float64
+		completed bool
+		required  *float64
+		projected *time.Time
+	}{
+		{
+			name:   "target date passed",
+			target: 1000, targetDate: date(2024, time.January, 1),
+			saved:     400,
+			remaining: 600, percent: 40,
+			required: ptr(600.0),
+		},
+		{
+			name:   "target date today",
+			target: 1000, targetDate: date(2024, time.March, 15),
+			saved:     400,
+			remaining: 600, percent: 40,
+			required: ptr(600.0),
+		},
+		{
+			name:   "target date in three months",
+			target: 1000, targetDate: date(2024, time.June, 14),
+			saved:     400,
+			remaining: 600, percent: 40,
+			required: ptr(200.0),
+		},
+		{
+			name:   "completed",
+			target: 1000, targetDate: date(2025, time.January, 1),
+			saved: 1500, recent: 900,
+			remaining: 0, percent: 100, completed: true,
+		},
+		{
+			name:      "no recent contributions",
+			target:    1000,
+			saved:     300,
+			remaining: 700, percent: 30,
+		},
+		{
+			name:   "projection from recent rate",
+			target: 1000,
+			saved:  100, recent: 90,
+			remaining: 900, percent: 10,
+			projected: date(2026, time.September, 1),
+		},
+		{
+			name:   "projection at the cutoff",
+			target: float64(maxProjectionDays),
+			recent: 90,
+			// Один в день: ровно maxProjectionDays дней.
+			remaining: float64(maxProjectionDays),
+			projected: ptr(truncateDay(today).AddDate(0, 0, maxProjectionDays)),
+		},
+		{
+			name:      "projection past the cutoff",
+			target:    float64(maxProjectionDays + 1),
+			recent:    90,
+			remaining: float64(maxProjectionDays + 1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goal := models.Goal{TargetAmount: tt.target, TargetDate: tt.targetDate}
+
+			got := Compute(goal, tt.saved, tt.recent, today)
+
+			if got.Saved != tt.saved {
+				t.Errorf("Saved = %v, want %v", got.Saved, tt.saved)
+			}
+			if got.Remaining != tt.remaining {
+				t.Errorf("Remaining = %v, want %v", got.Remaining, tt.remaining)
+			}
+			if !approx(got.Percent, tt.percent) {
+				t.Errorf("Percent = %v, want %v", got.Percent, tt.percent)
+			}
+			if got.Completed != tt.completed {
+				t.Errorf("Completed = %v, want %v", got.Completed, tt.completed)
+			}
+			if !approx(got.MonthlyRate, tt.recent/RecentDays*daysPerMonth) {
+				t.Errorf("MonthlyRate = %v", got.MonthlyRate)
+			}
+
+			switch {
+			case tt.required == nil && got.RequiredMonthly != nil:
+				t.Errorf("RequiredMonthly = %v, want nil", *got.RequiredMonthly)
+			case tt.required != nil && got.RequiredMonthly == nil:
+				t.Errorf("RequiredMonthly = nil, want %v", *tt.required)
+			case tt.required != nil && !approx(*got.RequiredMonthly, *tt.required):
+				t.Errorf("RequiredMonthly = %v, want %v", *got.RequiredMonthly, *tt.required)
+			}
+
+			switch {
+			case tt.projected == nil && got.ProjectedDate != nil:
+				t.Errorf("ProjectedDate = %v, want nil", *got.ProjectedDate)
+			case tt.projected != nil && got.ProjectedDate == nil:
+				t.Errorf("ProjectedDate = nil, want %v", *tt.projected)
+			case tt.projected != nil && !got.ProjectedDate.Equal(*tt.projected):
+				t.Errorf("ProjectedDate = %v, want %v", *got.ProjectedDate, *tt.projected)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	stored := time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		input models.GoalInput
+		start time.Time
+		code  string
+	}{
+		{"default start", models.GoalInput{}, stored, ""},
+		{"explicit start", models.GoalInput{StartDate: date(2024, time.January, 1)}, *date(2024, time.January, 1), ""},
+		{"target after default start", models.GoalInput{TargetDate: date(2023, time.June, 2)}, stored, ""},
+		{"target on default start", models.GoalInput{TargetDate: date(2023, time.June, 1)}, time.Time{}, "goal_invalid_target_date"},
+		{
+			"target before explicit start",
+			models.GoalInput{StartDate: date(2024, time.January, 1), TargetDate: date(2023, time.December, 1)},
+			time.Time{},
+			"goal_invalid_target_date",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goal, err := Resolve(FromInput(tt.input), stored.Add(15*time.Hour))
+
+			if tt.code != "" {
+				var domainErr *errs.Error
+				if !errors.As(err, &domainErr) || domainErr.Code != tt.code {
+					t.Fatalf("error = %v, want %s", err, tt.code)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !goal.StartDate.Equal(tt.start) {
+				t.Errorf("StartDate = %v, want %v", goal.StartDate, tt.start)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
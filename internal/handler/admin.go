@@ -0,0 +1,271 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"simple-finance/internal/auth"
+	"simple-finance/internal/cache"
+	"simple-finance/internal/handler/middleware"
+	"simple-finance/internal/handler/response"
+	"simple-finance/internal/models"
+	"simple-finance/internal/tokens"
+	"simple-finance/internal/validation"
+)
+
+const (
+	defaultUsersLimit = 50
+	maxUsersLimit     = 200
+)
+
+type AdminHandler struct {
+	validate     *validation.Validator
+	logger       *logrus.Logger
+	authManager  *auth.Manager
+	resetter     *auth.PasswordResetter
+	profileCache *cache.ProfileCache
+}
+
+func NewAdminHandler(
+	validate *validation.Validator,
+	logger *logrus.Logger,
+	authManager *auth.Manager,
+	resetter *auth.PasswordResetter,
+	profileCache *cache.ProfileCache,
+) *AdminHandler {
+	return &AdminHandler{
+		validate:     validate,
+		logger:       logger,
+		authManager:  authManager,
+		resetter:     resetter,
+		profileCache: profileCache,
+	}
+}
+
+// GetUsers             godoc
+// @Summary      List users
+// @Description  List and search users by username or email substring
+// @Tags         admin
+// @Produce      json
+// @Param        q       query  string  false  "Username or email substring"
+// @Param        limit   query  int     false  "Page size, 50 by default, at most 200"
+// @Param        offset  query  int     false  "Number of users to skip"
+// @Success      200  {object}  models.UserList
+// @Failure      400  {object}  response.Problem
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /admin/users [get]
+// @Security     Bearer
+func (h *AdminHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := models.UserFilter{
+		Query: query.Get("q"),
+		Limit: defaultUsersLimit,
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > maxUsersLimit {
+			response.BadRequest(w, "limit must be between 1 and 200")
+			return
+		}
+		filter.Limit = value
+	}
+
+	if offset := query.Get("offset"); offset != "" {
+		value, err := strconv.Atoi(offset)
+		if err != nil || value < 0 {
+			response.BadRequest(w, "offset must be a non-negative number")
+			return
+		}
+		filter.Offset = value
+	}
+
+	users, err := h.authManager.SearchUsers(r.Context(), filter)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(users)
+	if err != nil {
+		h.logger.Warn(err)
+		response.InternalServerError(w)
+		return
+	}
+
+	response.WriteResponse(w, http.StatusOK, resp)
+}
+
+// DisableUser             godoc
+// @Summary      Disable user
+// @Description  Disable the account and sign the user out everywhere
+// @Tags         admin
+// @Produce      json
+// @Param        id  path  string  true  "User ID"
+// @Success      200  {object}  response.IDResponse
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      409  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /admin/users/{id}/disable [post]
+// @Security     Bearer
+func (h *AdminHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserDisabled(w, r, true)
+}
+
+// EnableUser             godoc
+// @Summary      Enable user
+// @Description  Re-enable a disabled account
+// @Tags         admin
+// @Produce      json
+// @Param        id  path  string  true  "User ID"
+// @Success      200  {object}  response.IDResponse
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      409  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /admin/users/{id}/enable [post]
+// @Security     Bearer
+func (h *AdminHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserDisabled(w, r, false)
+}
+
+func (h *AdminHandler) setUserDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+
+	err := h.authManager.SetUserDisabled(r.Context(), tokenInfo.UserID, userID, disabled)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	response.IdResponse(w, userID)
+}
+
+// SetUserRole             godoc
+// @Summary      Change user role
+// @Description  Change the role of the user. The user has to sign in again
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id     path  string              true  "User ID"
+// @Param        input  body  models.SetRoleInput  true  "New role"
+// @Success      200  {object}  response.IDResponse
+// @Failure      400  {object}  response.Problem
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      409  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /admin/users/{id}/role [put]
+// @Security     Bearer
+func (h *AdminHandler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	var input models.SetRoleInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response.DecodeError(w, err)
+		return
+	}
+
+	err = h.validate.Struct(input, r.Header.Get("Accept-Language"))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+
+	err = h.authManager.SetUserRole(r.Context(), tokenInfo.UserID, userID, input.Role)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	// Роль и версия входят в профиль, иначе клиент получит устаревший ETag.
+	err = h.profileCache.Invalidate(r.Context(), userID)
+	if err != nil {
+		h.logger.Warn(err)
+	}
+
+	response.IdResponse(w, userID)
+}
+
+// ResetUserPassword             godoc
+// @Summary      Reset user password
+// @Description  Invalidate the current password, sign the user out and email a reset link
+// @Tags         admin
+// @Produce      json
+// @Param        id  path  string  true  "User ID"
+// @Success      200  {object}  response.IDResponse
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /admin/users/{id}/reset_password [post]
+// @Security     Bearer
+func (h *AdminHandler) ResetUserPassword(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+
+	err := h.resetter.ForceReset(r.Context(), userID)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	response.IdResponse(w, userID)
+}
+
+// GetStats             godoc
+// @Summary      System stats
+// @Description  Service-wide counters of users, transactions, sessions and API tokens
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  models.SystemStats
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /admin/stats [get]
+// @Security     Bearer
+func (h *AdminHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.authManager.SystemStats(r.Context())
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(stats)
+	if err != nil {
+		h.logger.Warn(err)
+		response.InternalServerError(w)
+		return
+	}
+
+	response.WriteResponse(w, http.StatusOK, resp)
+}
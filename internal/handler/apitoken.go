@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"simple-finance/internal/handler/middleware"
+	"simple-finance/internal/handler/response"
+	"simple-finance/internal/models"
+	"simple-finance/internal/tokens"
+)
+
+// CreateAPIToken             godoc
+// @Summary      Create personal access token
+// @Description  Create a named, scoped API token for scripts. The token is shown only once
+// @Tags         tokens
+// @Accept       json
+// @Produce      json
+// @Param        input  body  models.CreateAPITokenInput  true  "Token parameters"
+// @Success      200    {object}  models.CreatedAPIToken
+// @Failure      400    {object}  response.Problem
+// @Failure      401    {object}  response.Problem
+// @Failure      403    {object}  response.Problem
+// @Failure      409    {object}  response.Problem
+// @Failure      422    {object}  response.Problem
+// @Failure      500    {object}  response.Problem
+// @Router       /api/me/tokens [post]
+// @Security     Bearer
+func (h *AuthHandler) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	var input models.CreateAPITokenInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response.DecodeError(w, err)
+		return
+	}
+
+	err = h.validate.Struct(input, r.Header.Get("Accept-Language"))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	apiToken, err := h.authManager.CreateAPIToken(r.Context(), tokenInfo.UserID, input)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	ansBytes, err := json.Marshal(apiToken)
+	if err != nil {
+		h.logger.Warn(err)
+		response.InternalServerError(w)
+		return
+	}
+
+	response.WriteNoStore(w, http.StatusOK, ansBytes)
+}
+
+// GetAPITokens             godoc
+// @Summary      List personal access tokens
+// @Description  List API tokens of the authenticated user without their secrets
+// @Tags         tokens
+// @Produce      json
+// @Success      200  {array}   models.APIToken
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/me/tokens [get]
+// @Security     Bearer
+func (h *AuthHandler) GetAPITokens(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	apiTokens, err := h.authManager.APITokens(r.Context(), tokenInfo.UserID)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	ansBytes, err := json.Marshal(apiTokens)
+	if err != nil {
+		h.logger.Warn(err)
+		response.InternalServerError(w)
+		return
+	}
+
+	response.WriteResponse(w, http.StatusOK, ansBytes)
+}
+
+// RevokeAPIToken             godoc
+// @Summary      Revoke personal access token
+// @Description  Delete an API token, it stops working immediately
+// @Tags         tokens
+// @Produce      json
+// @Param        token_id  path  string  true  "Token ID"
+// @Success      200  {object}  response.IDResponse
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/me/tokens/{token_id} [delete]
+// @Security     Bearer
+func (h *AuthHandler) RevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	tokenID := chi.URLParam(r, "token_id")
+
+	err := h.authManager.RevokeAPIToken(r.Context(), tokenInfo.UserID, tokenID)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	response.IdResponse(w, tokenID)
+}
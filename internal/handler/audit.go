@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"simple-finance/internal/handler/middleware"
+	"simple-finance/internal/handler/response"
+	"simple-finance/internal/models"
+	"simple-finance/internal/tokens"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 200
+)
+
+// GetAuditLog             godoc
+// @Summary      Audit log
+// @Description  List changes of the user's own data and of ledgers the user belongs to, newest first
+// @Tags         profile
+// @Produce      json
+// @Param        entity     query  string  false  "Entity type, e.g. transaction, category, ledger, user"
+// @Param        entity_id  query  string  false  "Entity ID"
+// @Param        before_id  query  int     false  "Return entries older than this entry ID"
+// @Param        limit      query  int     false  "Page size, 50 by default, at most 200"
+// @Success      200  {array}   models.AuditEntry
+// @Failure      400  {object}  response.Problem
+// @Failure      401  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/audit [get]
+// @Security     Bearer
+func (h *ProfileHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	query := r.URL.Query()
+
+	filter := models.AuditFilter{
+		Entity:   query.Get("entity"),
+		EntityID: query.Get("entity_id"),
+		Limit:    defaultAuditLimit,
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > maxAuditLimit {
+			response.BadRequest(w, "limit must be between 1 and 200")
+			return
+		}
+		filter.Limit = value
+	}
+
+	if beforeID := query.Get("before_id"); beforeID != "" {
+		value, err := strconv.ParseInt(beforeID, 10, 64)
+		if err != nil || value < 1 {
+			response.BadRequest(w, "before_id must be a positive number")
+			return
+		}
+		filter.BeforeID = value
+	}
+
+	entries, err := h.db.GetAuditLog(r.Context(), tokenInfo.UserID, filter)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(entries)
+	if err != nil {
+		h.logger.Warn(err)
+		response.InternalServerError(w)
+		return
+	}
+
+	response.WriteResponse(w, http.StatusOK, resp)
+}
@@ -1,40 +1,47 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"time"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	_ "net/http"
 	"simple-finance/internal/auth"
 	"simple-finance/internal/db"
 	"simple-finance/internal/errs"
+	"simple-finance/internal/handler/middleware"
 	"simple-finance/internal/handler/response"
 	"simple-finance/internal/models"
+	"simple-finance/internal/tokens"
+	"simple-finance/internal/validation"
 	"simple-finance/pkg/hash"
 )
 
 type AuthHandler struct {
-	validate        *validator.Validate
+	validate        *validation.Validator
 	db              *db.FinanceDB
 	accessTokenTTL  time.Duration
 	refreshTokenTTL time.Duration
 	logger          *logrus.Logger
 	hasher          hash.PasswordHasher
 	authManager     *auth.Manager
+	verifier        *auth.EmailVerifier
+	resetter        *auth.PasswordResetter
+	mfa             *auth.MFAManager
 }
 
 func NewAuthHandler(
-	validate *validator.Validate,
+	validate *validation.Validator,
 	db *db.FinanceDB,
 	logger *logrus.Logger,
 	hasher hash.PasswordHasher,
 	authManager *auth.Manager,
+	verifier *auth.EmailVerifier,
+	resetter *auth.PasswordResetter,
+	mfa *auth.MFAManager,
 ) *AuthHandler {
 	return &AuthHandler{
 		validate:        validate,
@@ -44,70 +51,76 @@ func NewAuthHandler(
 		logger:          logger,
 		hasher:          hasher,
 		authManager:     authManager,
+		verifier:        verifier,
+		resetter:        resetter,
+		mfa:             mfa,
 	}
 }
 
 // SignIn             godoc
 // @Summary      Authenticate user
-// @Description  Login with username and password to get access and refresh tokens
+// @Description  Login with username or email and password to get access and refresh tokens. With 2FA enabled an MFA challenge is returned instead
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        input  body  models.SignInInput  true  "User credentials"
 // @Success      200    {object}  models.Tokens
-// @Failure      400    {object}  string
-// @Failure      401    {object}  string
-// @Failure      500    {object}  string
+// @Failure      400    {object}  response.Problem
+// @Failure      401    {object}  response.Problem
+// @Failure      422    {object}  response.Problem
+// @Failure      429    {object}  response.Problem
+// @Failure      500    {object}  response.Problem
 // @Router       /auth/sign_in [post]
 func (h *AuthHandler) SignIn(w http.ResponseWriter, r *http.Request) {
 	var input models.SignInInput
 
 	err := json.NewDecoder(r.Body).Decode(&input)
 	if err != nil {
-		response.BadRequest(w, err.Error())
+		response.DecodeError(w, err)
 		return
 	}
 
-	err = h.validate.Struct(input)
+	err = h.validate.Struct(input, r.Header.Get("Accept-Language"))
 	if err != nil {
-		response.BadRequest(w, err.Error())
+		response.Error(w, err)
 		return
 	}
 
-	ctx := context.Background()
+	ctx := r.Context()
 
-	userID, err := h.authManager.ComparePassword(ctx, input.Username, input.Password)
+	userID, err := h.authManager.ComparePassword(ctx, input.Identifier(), middleware.ClientIP(r), input.Password)
 	if err != nil {
 		h.logger.Warn(err)
-		if errors.Is(err, errs.ErrInvalidPassword) {
+		// Не сообщаем клиенту, существует ли пользователь с таким именем.
+		if errors.Is(err, errs.ErrInvalidPassword) || errors.Is(err, errs.ErrNotFound) {
 			response.Unauthorized(w)
 			return
 		}
 
-		response.InternalServerError(w)
+		response.Error(w, err)
 		return
 	}
 
-	accessToken, refreshToken, err := h.authManager.MakeTokens(userID, h.accessTokenTTL, h.refreshTokenTTL)
+	// С включённой 2FA токены выдаются только после проверки кода.
+	mfaToken, err := h.mfa.Challenge(ctx, userID)
 	if err != nil {
 		h.logger.Warn(err)
-		response.InternalServerError(w)
+		response.Error(w, err)
 		return
 	}
+	if mfaToken != "" {
+		ansBytes, err := json.Marshal(models.MFAChallenge{MFARequired: true, MFAToken: mfaToken})
+		if err != nil {
+			h.logger.Warn(err)
+			response.InternalServerError(w)
+			return
+		}
 
-	ansBytes, err := json.Marshal(
-		models.Tokens{
-			AccessToken:  accessToken,
-			RefreshToken: refreshToken,
-		},
-	)
-	if err != nil {
-		h.logger.Warn(err)
-		response.InternalServerError(w)
+		response.WriteResponse(w, http.StatusOK, ansBytes)
 		return
 	}
 
-	response.WriteResponse(w, http.StatusOK, ansBytes)
+	h.writeTokens(w, r, userID)
 }
 
 // SignUp             godoc
@@ -118,21 +131,23 @@ func (h *AuthHandler) SignIn(w http.ResponseWriter, r *http.Request) {
 // @Produce      json
 // @Param        input  body  models.SignUpInput  true  "User registration data"
 // @Success      201    {object}  models.UserInfo
-// @Failure      400    {object}  string
-// @Failure      500    {object}  string
+// @Failure      400    {object}  response.Problem
+// @Failure      409    {object}  response.Problem
+// @Failure      422    {object}  response.Problem
+// @Failure      500    {object}  response.Problem
 // @Router       /auth/sign_up [post]
 func (h *AuthHandler) SignUp(w http.ResponseWriter, r *http.Request) {
 	var input models.SignUpInput
 
 	err := json.NewDecoder(r.Body).Decode(&input)
 	if err != nil {
-		response.BadRequest(w, err.Error())
+		response.DecodeError(w, err)
 		return
 	}
 
-	err = h.validate.Struct(input)
+	err = h.validate.Struct(input, r.Header.Get("Accept-Language"))
 	if err != nil {
-		response.BadRequest(w, err.Error())
+		response.Error(w, err)
 		return
 	}
 
@@ -143,7 +158,7 @@ func (h *AuthHandler) SignUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := r.Context()
 	userInfo, err := h.db.InsertUser(ctx, models.UserInfo{
 		ID:       uuid.New().String(),
 		Email:    input.Email,
@@ -153,10 +168,16 @@ func (h *AuthHandler) SignUp(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		h.logger.Warn(err)
-		response.InternalServerError(w)
+		response.Error(w, err)
 		return
 	}
 
+	// Письмо можно запросить повторно, поэтому ошибка отправки не мешает регистрации.
+	err = h.verifier.SendVerification(ctx, userInfo.ID, userInfo.Email)
+	if err != nil {
+		h.logger.Warn(err)
+	}
+
 	ansBytes, err := json.Marshal(userInfo)
 	if err != nil {
 		h.logger.Warn(err)
@@ -172,27 +193,168 @@ func (h *AuthHandler) RefreshTokens(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&input)
 	if err != nil {
-		response.BadRequest(w, err.Error())
+		response.DecodeError(w, err)
 		return
 	}
 
-	err = h.validate.Struct(input)
+	err = h.validate.Struct(input, r.Header.Get("Accept-Language"))
 	if err != nil {
-		response.BadRequest(w, err.Error())
+		response.Error(w, err)
 		return
 	}
 
 	accessToken, refreshToken, err := h.authManager.RefreshTokens(
+		r.Context(),
 		input.RefreshToken,
+		clientInfo(r),
 		h.accessTokenTTL,
 		h.refreshTokenTTL,
 	)
 	if err != nil {
 		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	ansBytes, err := json.Marshal(
+		models.Tokens{
+			AccessToken:  accessToken,
+			RefreshToken: refreshToken,
+		},
+	)
+	if err != nil {
+		h.logger.Warn(err)
+		response.InternalServerError(w)
+		return
+	}
+
+	response.WriteResponse(w, http.StatusOK, ansBytes)
+}
+
+// ChangePassword             godoc
+// @Summary      Change password
+// @Description  Change password of the authenticated user. All previously issued tokens are revoked, new tokens are returned
+// @Tags         profile
+// @Accept       json
+// @Produce      json
+// @Param        input  body  models.ChangePasswordInput  true  "Current and new password"
+// @Success      200    {object}  models.Tokens
+// @Failure      400    {object}  response.Problem
+// @Failure      401    {object}  response.Problem
+// @Failure      422    {object}  response.Problem
+// @Failure      500    {object}  response.Problem
+// @Router       /api/me/password [put]
+// @Security     Bearer
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
 		response.InternalServerError(w)
 		return
 	}
 
+	var input models.ChangePasswordInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response.DecodeError(w, err)
+		return
+	}
+
+	err = h.validate.Struct(input, r.Header.Get("Accept-Language"))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	err = h.authManager.ChangePassword(r.Context(), tokenInfo.UserID, input.CurrentPassword, input.NewPassword)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	// Все сессии, включая текущую, отозваны — выдаём токены новой сессии.
+	h.writeTokens(w, r, tokenInfo.UserID)
+}
+
+// ForgotPassword             godoc
+// @Summary      Request password reset
+// @Description  Send a password reset link to the email. The response is the same whether the account exists or not
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        input  body  models.ForgotPasswordInput  true  "Account email"
+// @Success      200    {object}  response.Body
+// @Failure      400    {object}  response.Problem
+// @Failure      422    {object}  response.Problem
+// @Router       /auth/forgot_password [post]
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var input models.ForgotPasswordInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response.DecodeError(w, err)
+		return
+	}
+
+	err = h.validate.Struct(input, r.Header.Get("Accept-Language"))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	// Письмо уходит в фоне: ни ответ, ни время ответа не должны выдавать, есть ли такой аккаунт.
+	h.resetter.RequestReset(input.Email)
+
+	response.OKMessage(w, "if the account exists, a reset link has been sent")
+}
+
+// ResetPassword             godoc
+// @Summary      Reset password
+// @Description  Set a new password with the token from the reset email. All sessions are revoked
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        input  body  models.ResetPasswordInput  true  "Reset token and new password"
+// @Success      200    {object}  response.Body
+// @Failure      400    {object}  response.Problem
+// @Failure      422    {object}  response.Problem
+// @Failure      500    {object}  response.Problem
+// @Router       /auth/reset_password [post]
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var input models.ResetPasswordInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response.DecodeError(w, err)
+		return
+	}
+
+	err = h.validate.Struct(input, r.Header.Get("Accept-Language"))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	err = h.resetter.Reset(r.Context(), input.Token, input.NewPassword)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	response.OKMessage(w, "password has been changed")
+}
+
+// writeTokens starts a new session for the user and writes its tokens to the response.
+func (h *AuthHandler) writeTokens(w http.ResponseWriter, r *http.Request, userID string) {
+	accessToken, refreshToken, err := h.authManager.MakeTokens(r.Context(), userID, clientInfo(r), h.accessTokenTTL, h.refreshTokenTTL)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
 	ansBytes, err := json.Marshal(
 		models.Tokens{
 			AccessToken:  accessToken,
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"simple-finance/internal/handler/middleware"
+	"simple-finance/internal/handler/response"
+	"simple-finance/internal/models"
+	"simple-finance/internal/tokens"
+)
+
+// InsertTransactions             godoc
+// @Summary      Create transactions in bulk
+// @Description  Create up to 5000 transactions at once, all of them or none. ledger_id of each defaults to the personal ledger, a missing category_id is filled by rules or suggested from history. Requires the owner or editor role in every ledger
+// @Tags         transactions
+// @Accept       json
+// @Produce      json
+// @Param        input  body  models.BatchCreateInput  true  "Transactions"
+// @Success      200  {object}  models.BatchCreateResult
+// @Failure      400  {object}  response.Problem
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      413  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/transaction/batch [post]
+// @Security     Bearer
+func (h *TransactionHandler) InsertTransactions(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	var input models.BatchCreateInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response.DecodeError(w, err)
+		return
+	}
+
+	err = h.validator.Struct(input, r.Header.Get("Accept-Language"))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	for i := range input.Transactions {
+		input.Transactions[i].ID = uuid.New().String()
+		input.Transactions[i].UserID = tokenInfo.UserID
+		if input.Transactions[i].LedgerID == "" {
+			input.Transactions[i].LedgerID = tokenInfo.UserID
+		}
+	}
+
+	ids, err := h.db.InsertTransactions(r.Context(), input.Transactions)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(models.BatchCreateResult{IDs: ids})
+	if err != nil {
+		h.logger.Warn(err)
+		response.InternalServerError(w)
+		return
+	}
+
+	response.WriteResponse(w, http.StatusOK, resp)
+}
+
+// UpdateTransactions             godoc
+// @Summary      Update transactions in bulk
+// @Description  Recategorise or retag transactions selected by ids with the versions they were read at, or by filter. Transactions that cannot be changed or have changed since are reported and skipped
+// @Tags         transactions
+// @Accept       json
+// @Produce      json
+// @Param        input  body  models.BatchUpdateInput  true  "Selection and changes"
+// @Success      200  {object}  models.BatchResult
+// @Failure      400  {object}  response.Problem
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/transaction/batch [patch]
+// @Security     Bearer
+func (h *TransactionHandler) UpdateTransactions(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	var input models.BatchUpdateInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response.DecodeError(w, err)
+		return
+	}
+
+	err = h.validator.Struct(input, r.Header.Get("Accept-Language"))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	if input.CategoryID == nil && input.TagIDs == nil {
+		response.BadRequest(w, "nothing to update")
+		return
+	}
+
+	result, err := h.db.UpdateTransactions(r.Context(), tokenInfo.UserID, input)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	h.writeBatchResult(w, result)
+}
+
+// DeleteTransactions             godoc
+// @Summary      Delete transactions in bulk
+// @Description  Move transactions selected by ids with the versions they were read at, or by filter, to the trash. Transactions that cannot be deleted or have changed since are reported and skipped
+// @Tags         transactions
+// @Accept       json
+// @Produce      json
+// @Param        input  body  models.BatchDeleteInput  true  "Selection"
+// @Success      200  {object}  models.BatchResult
+// @Failure      400  {object}  response.Problem
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/transaction/batch/delete [post]
+// @Security     Bearer
+func (h *TransactionHandler) DeleteTransactions(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	var input models.BatchDeleteInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response.DecodeError(w, err)
+		return
+	}
+
+	err = h.validator.Struct(input, r.Header.Get("Accept-Language"))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	result, err := h.db.DeleteTransactions(r.Context(), tokenInfo.UserID, input)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	h.writeBatchResult(w, result)
+}
+
+func (h *TransactionHandler) writeBatchResult(w http.ResponseWriter, result models.BatchResult) {
+	resp, err := json.Marshal(result)
+	if err != nil {
+		h.logger.Warn(err)
+		response.InternalServerError(w)
+		return
+	}
+
+	response.WriteResponse(w, http.StatusOK, resp)
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"simple-finance/internal/db"
+	"simple-finance/internal/errs"
+	"simple-finance/internal/handler/response"
+)
+
+// ifMatchVersion reads the version the client has seen from If-Match. Changes
+// without the header are rejected, so a client cannot overwrite data it has
+// not read. It writes the error response and returns false on failure.
+func ifMatchVersion(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		response.WriteProblem(w, http.StatusPreconditionRequired, response.CodePreconditionRequired,
+			"If-Match header with the ETag of the resource is required")
+		return 0, false
+	}
+
+	if header == "*" {
+		return db.AnyVersion, true
+	}
+
+	// If-Match сравнивает теги строго, поэтому слабые теги (W/) не подходят.
+	unquoted, ok := strings.CutPrefix(header, `"`)
+	if ok {
+		unquoted, ok = strings.CutSuffix(unquoted, `"`)
+	}
+
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if !ok || err != nil || version < 1 {
+		response.Error(w, errs.PreconditionFailed("version_mismatch", "If-Match does not match the current ETag of the resource"))
+		return 0, false
+	}
+
+	return version, true
+}
+
+// profileETag takes the version from the encoded profile, which may come
+// from the cache.
+func profileETag(profile []byte) (string, error) {
+	var versioned struct {
+		Version int64 `json:"version"`
+	}
+
+	err := json.Unmarshal(profile, &versioned)
+	if err != nil {
+		return "", err
+	}
+
+	return response.ETag(versioned.Version), nil
+}
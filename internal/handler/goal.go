@@ -0,0 +1,385 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"simple-finance/internal/goals"
+	"simple-finance/internal/handler/middleware"
+	"simple-finance/internal/handler/response"
+	"simple-finance/internal/models"
+	"simple-finance/internal/tokens"
+)
+
+// GetGoals             godoc
+// @Summary      List goals
+// @Description  List savings goals of the ledger with their progress
+// @Tags         goals
+// @Produce      json
+// @Param        ledger_id      path    string  true   "Ledger ID"
+// @Param        If-None-Match  header  string  false  "ETag of the list the client already has"
+// @Success      200  {array}   models.Goal
+// @Success      304
+// @Failure      401  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/goals [get]
+// @Security     Bearer
+func (h *LedgerHandler) GetGoals(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	list, err := h.db.GetGoals(r.Context(), tokenInfo.UserID, chi.URLParam(r, "ledger_id"))
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	h.writeList(w, r, list)
+}
+
+// GetGoal             godoc
+// @Summary      Get goal
+// @Description  Get the savings goal with its progress. ETag is the goal version for If-Match
+// @Tags         goals
+// @Produce      json
+// @Param        ledger_id  path  string  true  "Ledger ID"
+// @Param        goal_id    path  string  true  "Goal ID"
+// @Success      200  {object}  models.Goal
+// @Failure      401  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/goals/{goal_id} [get]
+// @Security     Bearer
+func (h *LedgerHandler) GetGoal(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	goal, err := h.db.GetGoal(r.Context(), tokenInfo.UserID, chi.URLParam(r, "ledger_id"), chi.URLParam(r, "goal_id"))
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	h.writeGoal(w, goal)
+}
+
+// CreateGoal             godoc
+// @Summary      Create goal
+// @Description  Add a savings goal to the ledger. Requires the owner or editor role
+// @Tags         goals
+// @Accept       json
+// @Produce      json
+// @Param        ledger_id  path  string            true  "Ledger ID"
+// @Param        input      body  models.GoalInput  true  "Goal"
+// @Success      200  {object}  models.Goal
+// @Failure      400  {object}  response.Problem
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/goals [post]
+// @Security     Bearer
+func (h *LedgerHandler) CreateGoal(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	goal, ok := h.decodeGoal(w, r)
+	if !ok {
+		return
+	}
+
+	goal.ID = uuid.New().String()
+
+	goal, err := h.db.InsertGoal(r.Context(), tokenInfo.UserID, goal)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	h.writeGoal(w, goal)
+}
+
+// UpdateGoal             godoc
+// @Summary      Replace goal
+// @Description  Replace the goal definition. Without start_date the current one is kept. Contributions stay with the goal. Requires the owner or editor role
+// @Tags         goals
+// @Accept       json
+// @Produce      json
+// @Param        ledger_id  path    string            true  "Ledger ID"
+// @Param        goal_id    path    string            true  "Goal ID"
+// @Param        If-Match   header  string            true  "ETag of the goal, its version in quotes"
+// @Param        input      body    models.GoalInput  true  "Goal"
+// @Success      200  {object}  models.Goal
+// @Failure      400  {object}  response.Problem
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      412  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      428  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/goals/{goal_id} [put]
+// @Security     Bearer
+func (h *LedgerHandler) UpdateGoal(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	goal, ok := h.decodeGoal(w, r)
+	if !ok {
+		return
+	}
+
+	goal.ID = chi.URLParam(r, "goal_id")
+
+	goal, err := h.db.UpdateGoal(r.Context(), tokenInfo.UserID, goal, version)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	h.writeGoal(w, goal)
+}
+
+// DeleteGoal             godoc
+// @Summary      Delete goal
+// @Description  Delete the goal. Its contributions stay as ordinary incomes. Requires the owner or editor role
+// @Tags         goals
+// @Produce      json
+// @Param        ledger_id  path    string  true  "Ledger ID"
+// @Param        goal_id    path    string  true  "Goal ID"
+// @Param        If-Match   header  string  true  "ETag of the goal, its version in quotes"
+// @Success      200  {object}  response.IDResponse
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      412  {object}  response.Problem
+// @Failure      428  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/goals/{goal_id} [delete]
+// @Security     Bearer
+func (h *LedgerHandler) DeleteGoal(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	goalID := chi.URLParam(r, "goal_id")
+
+	err := h.db.DeleteGoal(r.Context(), tokenInfo.UserID, chi.URLParam(r, "ledger_id"), goalID, version)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	response.IdResponse(w, goalID)
+}
+
+// GetContributions             godoc
+// @Summary      List contributions
+// @Description  List incomes recorded for the goal and transactions of its category since the start date, newest first
+// @Tags         goals
+// @Produce      json
+// @Param        ledger_id      path    string  true   "Ledger ID"
+// @Param        goal_id        path    string  true   "Goal ID"
+// @Param        If-None-Match  header  string  false  "ETag of the list the client already has"
+// @Success      200  {array}   models.Contribution
+// @Success      304
+// @Failure      401  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/goals/{goal_id}/contributions [get]
+// @Security     Bearer
+func (h *LedgerHandler) GetContributions(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	list, err := h.db.GetContributions(r.Context(), tokenInfo.UserID, chi.URLParam(r, "ledger_id"), chi.URLParam(r, "goal_id"))
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	h.writeList(w, r, list)
+}
+
+// AddContribution             godoc
+// @Summary      Add contribution
+// @Description  Record your income towards the goal. Requires the owner or editor role
+// @Tags         goals
+// @Accept       json
+// @Produce      json
+// @Param        ledger_id  path  string                    true  "Ledger ID"
+// @Param        goal_id    path  string                    true  "Goal ID"
+// @Param        input      body  models.ContributionInput  true  "Contribution"
+// @Success      200  {object}  models.Contribution
+// @Failure      400  {object}  response.Problem
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/goals/{goal_id}/contributions [post]
+// @Security     Bearer
+func (h *LedgerHandler) AddContribution(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	var input models.ContributionInput
+	if !h.decode(w, r, &input) {
+		return
+	}
+
+	contribution, err := h.db.AddContribution(
+		r.Context(),
+		tokenInfo.UserID,
+		chi.URLParam(r, "ledger_id"),
+		chi.URLParam(r, "goal_id"),
+		input,
+	)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	h.writeJSON(w, contribution)
+}
+
+// DeleteContribution             godoc
+// @Summary      Delete contribution
+// @Description  Move an income recorded for the goal to the trash. Transfers are deleted as transactions. Requires the owner or editor role
+// @Tags         goals
+// @Produce      json
+// @Param        ledger_id        path  string  true  "Ledger ID"
+// @Param        goal_id          path  string  true  "Goal ID"
+// @Param        contribution_id  path  string  true  "Contribution ID"
+// @Success      200  {object}  response.IDResponse
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/goals/{goal_id}/contributions/{contribution_id} [delete]
+// @Security     Bearer
+func (h *LedgerHandler) DeleteContribution(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	contributionID := chi.URLParam(r, "contribution_id")
+
+	err := h.db.DeleteContribution(
+		r.Context(),
+		tokenInfo.UserID,
+		chi.URLParam(r, "ledger_id"),
+		chi.URLParam(r, "goal_id"),
+		contributionID,
+	)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	response.IdResponse(w, contributionID)
+}
+
+// RestoreContribution             godoc
+// @Summary      Restore contribution
+// @Description  Return an income recorded for the goal from the trash. Requires the owner or editor role
+// @Tags         trash
+// @Produce      json
+// @Param        ledger_id        path  string  true  "Ledger ID"
+// @Param        goal_id          path  string  true  "Goal ID"
+// @Param        contribution_id  path  string  true  "Contribution ID"
+// @Success      200  {object}  response.IDResponse
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/goals/{goal_id}/contributions/{contribution_id}/restore [post]
+// @Security     Bearer
+func (h *LedgerHandler) RestoreContribution(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	contributionID := chi.URLParam(r, "contribution_id")
+
+	err := h.db.RestoreContribution(
+		r.Context(),
+		tokenInfo.UserID,
+		chi.URLParam(r, "ledger_id"),
+		chi.URLParam(r, "goal_id"),
+		contributionID,
+	)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	response.IdResponse(w, contributionID)
+}
+
+// decodeGoal reads a goal definition for the ledger from the URL. Dates are
+// checked by the database layer, which knows the stored start date.
+func (h *LedgerHandler) decodeGoal(w http.ResponseWriter, r *http.Request) (models.Goal, bool) {
+	var input models.GoalInput
+	if !h.decode(w, r, &input) {
+		return models.Goal{}, false
+	}
+
+	goal := goals.FromInput(input)
+	goal.LedgerID = chi.URLParam(r, "ledger_id")
+
+	return goal, true
+}
+
+// writeGoal отдаёт версию цели в ETag для If-Match. If-None-Match не
+// поддерживаем: прогресс меняется со взносами, а версия — нет.
+func (h *LedgerHandler) writeGoal(w http.ResponseWriter, goal models.Goal) {
+	w.Header().Set("ETag", response.ETag(goal.Version))
+	h.writeJSON(w, goal)
+}
@@ -0,0 +1,615 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"simple-finance/internal/db"
+	"simple-finance/internal/handler/middleware"
+	"simple-finance/internal/handler/response"
+	"simple-finance/internal/models"
+	"simple-finance/internal/tokens"
+	"simple-finance/internal/validation"
+)
+
+// LedgerHandler manages shared ledgers, their members, categories and rules.
+// Member roles are checked by FinanceDB in the same queries that read or
+// change the data.
+type LedgerHandler struct {
+	db        *db.FinanceDB
+	validator *validation.Validator
+	logger    *logrus.Logger
+}
+
+func NewLedgerHandler(
+	db *db.FinanceDB,
+	validator *validation.Validator,
+	logger *logrus.Logger,
+) *LedgerHandler {
+	return &LedgerHandler{
+		db:        db,
+		validator: validator,
+		logger:    logger,
+	}
+}
+
+// GetLedgers             godoc
+// @Summary      List ledgers
+// @Description  List ledgers the user is a member of, the personal ledger first
+// @Tags         ledgers
+// @Produce      json
+// @Param        If-None-Match  header  string  false  "ETag of the list the client already has"
+// @Success      200  {array}   models.Ledger
+// @Success      304
+// @Failure      401  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers [get]
+// @Security     Bearer
+func (h *LedgerHandler) GetLedgers(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	ledgers, err := h.db.GetLedgers(r.Context(), tokenInfo.UserID)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	h.writeList(w, r, ledgers)
+}
+
+// CreateLedger             godoc
+// @Summary      Create ledger
+// @Description  Create a shared ledger, the user becomes its owner
+// @Tags         ledgers
+// @Accept       json
+// @Produce      json
+// @Param        input  body  models.LedgerInput  true  "Ledger name"
+// @Success      200    {object}  models.Ledger
+// @Failure      400    {object}  response.Problem
+// @Failure      401    {object}  response.Problem
+// @Failure      422    {object}  response.Problem
+// @Failure      500    {object}  response.Problem
+// @Router       /api/ledgers [post]
+// @Security     Bearer
+func (h *LedgerHandler) CreateLedger(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	var input models.LedgerInput
+	if !h.decode(w, r, &input) {
+		return
+	}
+
+	ledger, err := h.db.CreateLedger(r.Context(), tokenInfo.UserID, input.Name)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	h.writeJSON(w, ledger)
+}
+
+// RenameLedger             godoc
+// @Summary      Rename ledger
+// @Description  Change the name of the ledger. Requires the owner role
+// @Tags         ledgers
+// @Accept       json
+// @Produce      json
+// @Param        ledger_id  path    string              true  "Ledger ID"
+// @Param        If-Match   header  string              true  "ETag of the ledger, its version in quotes"
+// @Param        input      body    models.LedgerInput  true  "Ledger name"
+// @Success      200  {object}  response.IDResponse
+// @Failure      400  {object}  response.Problem
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      412  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      428  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id} [patch]
+// @Security     Bearer
+func (h *LedgerHandler) RenameLedger(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	var input models.LedgerInput
+	if !h.decode(w, r, &input) {
+		return
+	}
+
+	ledgerID := chi.URLParam(r, "ledger_id")
+
+	err := h.db.RenameLedger(r.Context(), tokenInfo.UserID, ledgerID, input.Name, version)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	response.IdResponse(w, ledgerID)
+}
+
+// DeleteLedger             godoc
+// @Summary      Delete ledger
+// @Description  Delete a shared ledger with all of its transactions and categories. Requires the owner role
+// @Tags         ledgers
+// @Produce      json
+// @Param        ledger_id  path    string  true  "Ledger ID"
+// @Param        If-Match   header  string  true  "ETag of the ledger, its version in quotes"
+// @Success      200  {object}  response.IDResponse
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      409  {object}  response.Problem
+// @Failure      412  {object}  response.Problem
+// @Failure      428  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id} [delete]
+// @Security     Bearer
+func (h *LedgerHandler) DeleteLedger(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	ledgerID := chi.URLParam(r, "ledger_id")
+
+	err := h.db.DeleteLedger(r.Context(), tokenInfo.UserID, ledgerID, version)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	response.IdResponse(w, ledgerID)
+}
+
+// GetMembers             godoc
+// @Summary      List ledger members
+// @Description  List members of the ledger with their roles
+// @Tags         ledgers
+// @Produce      json
+// @Param        ledger_id  path  string  true  "Ledger ID"
+// @Success      200  {array}   models.LedgerMember
+// @Failure      401  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/members [get]
+// @Security     Bearer
+func (h *LedgerHandler) GetMembers(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	members, err := h.db.GetLedgerMembers(r.Context(), tokenInfo.UserID, chi.URLParam(r, "ledger_id"))
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	h.writeJSON(w, members)
+}
+
+// SetMemberRole             godoc
+// @Summary      Change member role
+// @Description  Change the role of a ledger member. Requires the owner role, the ledger keeps at least one owner
+// @Tags         ledgers
+// @Accept       json
+// @Produce      json
+// @Param        ledger_id  path  string                  true  "Ledger ID"
+// @Param        user_id    path  string                  true  "Member user ID"
+// @Param        input      body  models.MemberRoleInput  true  "New role"
+// @Success      200  {object}  response.IDResponse
+// @Failure      400  {object}  response.Problem
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      409  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/members/{user_id} [put]
+// @Security     Bearer
+func (h *LedgerHandler) SetMemberRole(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	var input models.MemberRoleInput
+	if !h.decode(w, r, &input) {
+		return
+	}
+
+	memberID := chi.URLParam(r, "user_id")
+
+	err := h.db.SetLedgerMemberRole(r.Context(), tokenInfo.UserID, chi.URLParam(r, "ledger_id"), memberID, input.Role)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	response.IdResponse(w, memberID)
+}
+
+// RemoveMember             godoc
+// @Summary      Remove ledger member
+// @Description  Owners can remove any member, other members can only leave the ledger themselves
+// @Tags         ledgers
+// @Produce      json
+// @Param        ledger_id  path  string  true  "Ledger ID"
+// @Param        user_id    path  string  true  "Member user ID"
+// @Success      200  {object}  response.IDResponse
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      409  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/members/{user_id} [delete]
+// @Security     Bearer
+func (h *LedgerHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	memberID := chi.URLParam(r, "user_id")
+
+	err := h.db.RemoveLedgerMember(r.Context(), tokenInfo.UserID, chi.URLParam(r, "ledger_id"), memberID)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	response.IdResponse(w, memberID)
+}
+
+// Invite             godoc
+// @Summary      Invite to ledger
+// @Description  Invite a user by username or email. Requires the owner role
+// @Tags         ledgers
+// @Accept       json
+// @Produce      json
+// @Param        ledger_id  path  string              true  "Ledger ID"
+// @Param        input      body  models.InviteInput  true  "Invited user and role"
+// @Success      200  {object}  response.IDResponse
+// @Failure      400  {object}  response.Problem
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      409  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/invitations [post]
+// @Security     Bearer
+func (h *LedgerHandler) Invite(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	var input models.InviteInput
+	if !h.decode(w, r, &input) {
+		return
+	}
+
+	invitationID, err := h.db.InviteToLedger(r.Context(), tokenInfo.UserID, chi.URLParam(r, "ledger_id"), input)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	response.IdResponse(w, invitationID)
+}
+
+// GetInvitations             godoc
+// @Summary      List invitations
+// @Description  List pending invitations of the user to other ledgers
+// @Tags         ledgers
+// @Produce      json
+// @Success      200  {array}   models.Invitation
+// @Failure      401  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/invitations [get]
+// @Security     Bearer
+func (h *LedgerHandler) GetInvitations(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	invitations, err := h.db.GetInvitations(r.Context(), tokenInfo.UserID)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	h.writeJSON(w, invitations)
+}
+
+// AcceptInvitation             godoc
+// @Summary      Accept invitation
+// @Description  Join the ledger with the role from the invitation
+// @Tags         ledgers
+// @Produce      json
+// @Param        invitation_id  path  string  true  "Invitation ID"
+// @Success      200  {object}  response.IDResponse
+// @Failure      401  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/invitations/{invitation_id}/accept [post]
+// @Security     Bearer
+func (h *LedgerHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	ledgerID, err := h.db.AcceptInvitation(r.Context(), tokenInfo.UserID, chi.URLParam(r, "invitation_id"))
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	response.IdResponse(w, ledgerID)
+}
+
+// DeclineInvitation             godoc
+// @Summary      Decline invitation
+// @Description  Delete the invitation without joining the ledger
+// @Tags         ledgers
+// @Produce      json
+// @Param        invitation_id  path  string  true  "Invitation ID"
+// @Success      200  {object}  response.IDResponse
+// @Failure      401  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/invitations/{invitation_id} [delete]
+// @Security     Bearer
+func (h *LedgerHandler) DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	invitationID := chi.URLParam(r, "invitation_id")
+
+	err := h.db.DeclineInvitation(r.Context(), tokenInfo.UserID, invitationID)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	response.IdResponse(w, invitationID)
+}
+
+// GetCategories             godoc
+// @Summary      List categories
+// @Description  List transaction categories of the ledger
+// @Tags         ledgers
+// @Produce      json
+// @Param        ledger_id      path    string  true   "Ledger ID"
+// @Param        If-None-Match  header  string  false  "ETag of the list the client already has"
+// @Success      200  {array}   models.Category
+// @Success      304
+// @Failure      401  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/categories [get]
+// @Security     Bearer
+func (h *LedgerHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	categories, err := h.db.GetCategories(r.Context(), tokenInfo.UserID, chi.URLParam(r, "ledger_id"))
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	h.writeList(w, r, categories)
+}
+
+// InsertCategory             godoc
+// @Summary      Create category
+// @Description  Add a transaction category to the ledger. Requires the owner or editor role
+// @Tags         ledgers
+// @Accept       json
+// @Produce      json
+// @Param        ledger_id  path  string           true  "Ledger ID"
+// @Param        input      body  models.Category  true  "Category"
+// @Success      200  {object}  response.IDResponse
+// @Failure      400  {object}  response.Problem
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/categories [post]
+// @Security     Bearer
+func (h *LedgerHandler) InsertCategory(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	var category models.Category
+	if !h.decode(w, r, &category) {
+		return
+	}
+
+	category.ID = uuid.New().String()
+	category.LedgerID = chi.URLParam(r, "ledger_id")
+
+	categoryID, err := h.db.InsertCategory(r.Context(), tokenInfo.UserID, category)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	response.IdResponse(w, categoryID)
+}
+
+// DeleteCategory             godoc
+// @Summary      Delete category
+// @Description  Move a category without transactions to the trash. Requires the owner or editor role
+// @Tags         ledgers
+// @Produce      json
+// @Param        ledger_id    path    string  true  "Ledger ID"
+// @Param        category_id  path    string  true  "Category ID"
+// @Param        If-Match     header  string  true  "ETag of the category, its version in quotes"
+// @Success      200  {object}  response.IDResponse
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      409  {object}  response.Problem
+// @Failure      412  {object}  response.Problem
+// @Failure      428  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/categories/{category_id} [delete]
+// @Security     Bearer
+func (h *LedgerHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	categoryID := chi.URLParam(r, "category_id")
+
+	err := h.db.DeleteCategory(r.Context(), tokenInfo.UserID, chi.URLParam(r, "ledger_id"), categoryID, version)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	response.IdResponse(w, categoryID)
+}
+
+// RestoreCategory             godoc
+// @Summary      Restore category
+// @Description  Return a deleted category from the trash. Requires the owner or editor role
+// @Tags         trash
+// @Produce      json
+// @Param        ledger_id    path  string  true  "Ledger ID"
+// @Param        category_id  path  string  true  "Category ID"
+// @Success      200  {object}  response.IDResponse
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/categories/{category_id}/restore [post]
+// @Security     Bearer
+func (h *LedgerHandler) RestoreCategory(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	categoryID := chi.URLParam(r, "category_id")
+
+	err := h.db.RestoreCategory(r.Context(), tokenInfo.UserID, chi.URLParam(r, "ledger_id"), categoryID)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	response.IdResponse(w, categoryID)
+}
+
+// decode reads and validates the request body. It writes the error response
+// and returns false when the body is invalid.
+func (h *LedgerHandler) decode(w http.ResponseWriter, r *http.Request, input any) bool {
+	err := json.NewDecoder(r.Body).Decode(input)
+	if err != nil {
+		response.DecodeError(w, err)
+		return false
+	}
+
+	err = h.validator.Struct(input, r.Header.Get("Accept-Language"))
+	if err != nil {
+		response.Error(w, err)
+		return false
+	}
+
+	return true
+}
+
+func (h *LedgerHandler) writeJSON(w http.ResponseWriter, v any) {
+	resp, err := json.Marshal(v)
+	if err != nil {
+		h.logger.Warn(err)
+		response.InternalServerError(w)
+		return
+	}
+
+	response.WriteResponse(w, http.StatusOK, resp)
+}
+
+// writeList writes a list with an ETag derived from its content, so clients
+// can poll it with If-None-Match.
+func (h *LedgerHandler) writeList(w http.ResponseWriter, r *http.Request, v any) {
+	resp, err := json.Marshal(v)
+	if err != nil {
+		h.logger.Warn(err)
+		response.InternalServerError(w)
+		return
+	}
+
+	response.WriteWithETag(w, r, response.ContentETag(resp), resp)
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"simple-finance/internal/errs"
+	"simple-finance/internal/handler/middleware"
+	"simple-finance/internal/handler/response"
+	"simple-finance/internal/models"
+	"simple-finance/internal/tokens"
+)
+
+// SignInMFA             godoc
+// @Summary      Second sign-in step
+// @Description  Exchange the MFA challenge token and a TOTP or recovery code for access and refresh tokens
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        input  body  models.SignInMFAInput  true  "Challenge token and code"
+// @Success      200    {object}  models.Tokens
+// @Failure      400    {object}  response.Problem
+// @Failure      401    {object}  response.Problem
+// @Failure      422    {object}  response.Problem
+// @Failure      429    {object}  response.Problem
+// @Failure      500    {object}  response.Problem
+// @Router       /auth/sign_in/mfa [post]
+func (h *AuthHandler) SignInMFA(w http.ResponseWriter, r *http.Request) {
+	var input models.SignInMFAInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response.DecodeError(w, err)
+		return
+	}
+
+	err = h.validate.Struct(input, r.Header.Get("Accept-Language"))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	userID, err := h.mfa.VerifyChallenge(r.Context(), input.MFAToken, input.Code, input.RecoveryCode)
+	if err != nil {
+		h.logger.Warn(err)
+		if errors.Is(err, errs.ErrInvalidToken) {
+			response.Unauthorized(w)
+			return
+		}
+
+		response.Error(w, err)
+		return
+	}
+
+	h.writeTokens(w, r, userID)
+}
+
+// EnrollMFA             godoc
+// @Summary      Start 2FA enrolment
+// @Description  Generate a TOTP secret for an authenticator app. 2FA is enabled only after confirmation
+// @Tags         profile
+// @Produce      json
+// @Success      200  {object}  models.MFAEnrollment
+// @Failure      401  {object}  response.Problem
+// @Failure      409  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/me/2fa [post]
+// @Security     Bearer
+func (h *AuthHandler) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	ctx := r.Context()
+	userInfo, err := h.db.GetUserById(ctx, tokenInfo.UserID)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	secret, uri, err := h.mfa.Enroll(ctx, userInfo.ID, userInfo.Email)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	ansBytes, err := json.Marshal(models.MFAEnrollment{Secret: secret, OTPAuthURI: uri})
+	if err != nil {
+		h.logger.Warn(err)
+		response.InternalServerError(w)
+		return
+	}
+
+	response.WriteNoStore(w, http.StatusOK, ansBytes)
+}
+
+// ConfirmMFA             godoc
+// @Summary      Confirm 2FA enrolment
+// @Description  Enable 2FA with a code from the authenticator app. Recovery codes are returned only once
+// @Tags         profile
+// @Accept       json
+// @Produce      json
+// @Param        input  body  models.ConfirmMFAInput  true  "TOTP code"
+// @Success      200    {object}  models.RecoveryCodes
+// @Failure      400    {object}  response.Problem
+// @Failure      401    {object}  response.Problem
+// @Failure      409    {object}  response.Problem
+// @Failure      422    {object}  response.Problem
+// @Failure      500    {object}  response.Problem
+// @Router       /api/me/2fa/confirm [post]
+// @Security     Bearer
+func (h *AuthHandler) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	var input models.ConfirmMFAInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response.DecodeError(w, err)
+		return
+	}
+
+	err = h.validate.Struct(input, r.Header.Get("Accept-Language"))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	codes, err := h.mfa.Confirm(r.Context(), tokenInfo.UserID, input.Code)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	ansBytes, err := json.Marshal(models.RecoveryCodes{RecoveryCodes: codes})
+	if err != nil {
+		h.logger.Warn(err)
+		response.InternalServerError(w)
+		return
+	}
+
+	response.WriteNoStore(w, http.StatusOK, ansBytes)
+}
+
+// DisableMFA             godoc
+// @Summary      Disable 2FA
+// @Description  Turn 2FA off. Requires the current password and a TOTP code
+// @Tags         profile
+// @Accept       json
+// @Produce      json
+// @Param        input  body  models.DisableMFAInput  true  "Password and TOTP code"
+// @Success      200    {object}  response.Body
+// @Failure      400    {object}  response.Problem
+// @Failure      401    {object}  response.Problem
+// @Failure      409    {object}  response.Problem
+// @Failure      422    {object}  response.Problem
+// @Failure      500    {object}  response.Problem
+// @Router       /api/me/2fa [delete]
+// @Security     Bearer
+func (h *AuthHandler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	var input models.DisableMFAInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response.DecodeError(w, err)
+		return
+	}
+
+	err = h.validate.Struct(input, r.Header.Get("Accept-Language"))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	ctx := r.Context()
+	err = h.authManager.CheckPassword(ctx, tokenInfo.UserID, input.Password)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	err = h.mfa.Disable(ctx, tokenInfo.UserID, input.Code)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	response.OKMessage(w, "two-factor authentication disabled")
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+
+	m "github.com/go-chi/chi/v5/middleware"
+	"simple-finance/internal/db"
+	"simple-finance/internal/tokens"
+)
+
+// Размер колонки ip в таблице audit_log.
+const maxAuditIPLength = 45
+
+// ClientIP returns the client address without the port. It relies on chi's
+// RealIP middleware to put the client address into RemoteAddr.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// RealIP подставляет адрес без порта.
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// AuditMeta puts the author, request ID and client address of the request
+// into the context, so changes made by the request are attributed in the
+// audit log. On authenticated routes it has to run after AuthMiddleware.MakeAuth.
+func AuditMeta(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		// Анонимные запросы (регистрация, сброс пароля) тоже пишут в журнал, но без автора.
+		tokenInfo, _ := ctx.Value(TokenInfoKey).(tokens.TokenInfo)
+
+		ip := ClientIP(r)
+		if len(ip) > maxAuditIPLength {
+			ip = ip[:maxAuditIPLength]
+		}
+
+		ctx = db.WithAuditMeta(ctx, db.AuditMeta{
+			ActorID:   tokenInfo.UserID,
+			RequestID: m.GetReqID(ctx),
+			IP:        ip,
+		})
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
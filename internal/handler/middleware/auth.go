@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strings"
 
+	"simple-finance/internal/auth"
 	"simple-finance/internal/handler/response"
 	"simple-finance/internal/tokens"
 )
@@ -18,19 +19,19 @@ const (
 )
 
 type AuthMiddleware struct {
-	tokenManager *tokens.TokenManager
+	authManager *auth.Manager
 }
 
-func NewAuthMiddleware(tokenManager *tokens.TokenManager) *AuthMiddleware {
+func NewAuthMiddleware(authManager *auth.Manager) *AuthMiddleware {
 	return &AuthMiddleware{
-		tokenManager: tokenManager,
+		authManager: authManager,
 	}
 }
 
 func (h *AuthMiddleware) MakeAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get(authorizationHeader)
-		tokenInfo, err := h.parseAuthHeader(authHeader)
+		tokenInfo, err := h.parseAuthHeader(r.Context(), authHeader)
 
 		if err != nil {
 			response.Unauthorized(w)
@@ -42,7 +43,7 @@ func (h *AuthMiddleware) MakeAuth(next http.Handler) http.Handler {
 	})
 }
 
-func (h *AuthMiddleware) parseAuthHeader(authHeader string) (tokens.TokenInfo, error) {
+func (h *AuthMiddleware) parseAuthHeader(ctx context.Context, authHeader string) (tokens.TokenInfo, error) {
 	if authHeader == "" {
 		return tokens.TokenInfo{}, errors.New("empty auth header")
 	}
@@ -57,5 +58,5 @@ func (h *AuthMiddleware) parseAuthHeader(authHeader string) (tokens.TokenInfo, e
 	}
 	accessToken := headerParts[1]
 
-	return h.tokenManager.Parse(accessToken)
+	return h.authManager.ParseToken(ctx, accessToken)
 }
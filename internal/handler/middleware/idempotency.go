@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	m "github.com/go-chi/chi/v5/middleware"
+	"github.com/sirupsen/logrus"
+	"simple-finance/internal/cache"
+	"simple-finance/internal/errs"
+	"simple-finance/internal/handler/response"
+	"simple-finance/internal/tokens"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+type IdempotencyMiddleware struct {
+	store   *cache.IdempotencyStore
+	logger  *logrus.Logger
+	ttl     time.Duration
+	lockTTL time.Duration
+}
+
+// NewIdempotencyMiddleware stores responses for ttl. lockTTL should be at
+// least the longest time a request may take.
+func NewIdempotencyMiddleware(
+	store *cache.IdempotencyStore,
+	logger *logrus.Logger,
+	ttl, lockTTL time.Duration,
+) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		store:   store,
+		logger:  logger,
+		ttl:     ttl,
+		lockTTL: lockTTL,
+	}
+}
+
+// Handle replays the stored response to a POST request retried with the same
+// Idempotency-Key header. Keys are scoped to the user, so it has to run after
+// AuthMiddleware.MakeAuth. Requests without the header are not affected.
+// Responses marked Cache-Control: no-store are not replayed.
+func (h *IdempotencyMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
+		if r.Method != http.MethodPost || idempotencyKey == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			response.BadRequest(w, "Idempotency-Key must be at most 255 characters")
+			return
+		}
+
+		tokenInfo, ok := r.Context().Value(TokenInfoKey).(tokens.TokenInfo)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			response.DecodeError(w, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := tokenInfo.UserID + ":" + idempotencyKey
+		fingerprint := requestFingerprint(r, body)
+
+		stored, started, err := h.store.Begin(r.Context(), key, fingerprint, h.lockTTL)
+		if err != nil {
+			// Без Redis запрос выполняется как обычно, просто без защиты от повторов.
+			h.logger.Warn(err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !started {
+			replay(w, stored, fingerprint)
+			return
+		}
+
+		ww := m.NewWrapResponseWriter(w, r.ProtoMajor)
+		var buf bytes.Buffer
+		ww.Tee(&buf)
+
+		// Запрос может быть отменён клиентом, а ключ всё равно нужно сохранить или освободить.
+		ctx := context.WithoutCancel(r.Context())
+
+		defer func() {
+			if p := recover(); p != nil {
+				h.release(ctx, key)
+				panic(p)
+			}
+		}()
+
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		// Ошибки сервера не запоминаем, чтобы повтор мог выполниться успешно.
+		if status >= http.StatusInternalServerError {
+			h.release(ctx, key)
+			return
+		}
+
+		stored = cache.IdempotentResponse{
+			Fingerprint: fingerprint,
+			Status:      status,
+			Header:      map[string]string{"Content-Type": ww.Header().Get("Content-Type")},
+			Body:        buf.Bytes(),
+		}
+		// Секреты, которые показываются один раз, в Redis не попадают. Повтор
+		// с тем же ключом всё равно не выполнит запрос второй раз.
+		if strings.Contains(ww.Header().Get("Cache-Control"), "no-store") {
+			stored = cache.IdempotentResponse{Fingerprint: fingerprint, Status: status, NoStore: true}
+		}
+
+		err = h.store.Complete(ctx, key, stored, h.ttl)
+		if err != nil {
+			h.logger.Warn(err)
+			h.release(ctx, key)
+		}
+	})
+}
+
+func (h *IdempotencyMiddleware) release(ctx context.Context, key string) {
+	err := h.store.Release(ctx, key)
+	if err != nil {
+		h.logger.Warn(err)
+	}
+}
+
+func replay(w http.ResponseWriter, stored cache.IdempotentResponse, fingerprint string) {
+	if stored.Fingerprint != fingerprint {
+		response.Error(w, errs.Validation(
+			"idempotency_key_reused",
+			"the idempotency key was already used for a different request",
+		))
+		return
+	}
+
+	if stored.Pending {
+		response.Error(w, errs.Conflict(
+			"idempotency_key_in_use",
+			"a request with this idempotency key is still in progress",
+		))
+		return
+	}
+
+	if stored.NoStore {
+		response.Error(w, errs.Conflict(
+			"idempotency_key_used",
+			"the request with this idempotency key was already completed, its response is shown only once",
+		))
+		return
+	}
+
+	for name, value := range stored.Header {
+		if value != "" {
+			w.Header().Set(name, value)
+		}
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(stored.Status)
+
+	_, _ = w.Write(stored.Body)
+}
+
+// requestFingerprint identifies the request by its route and body, so the same
+// key sent to another endpoint or with another body is detected.
+func requestFingerprint(r *http.Request, body []byte) string {
+	sum := sha256.New()
+	sum.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	sum.Write(body)
+
+	return hex.EncodeToString(sum.Sum(nil))
+}
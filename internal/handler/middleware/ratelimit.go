@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"simple-finance/internal/cache"
+	"simple-finance/internal/config"
+	"simple-finance/internal/errs"
+	"simple-finance/internal/handler/response"
+	"simple-finance/internal/tokens"
+)
+
+type RateLimitMiddleware struct {
+	limiter *cache.RateLimiter
+	logger  *logrus.Logger
+	enabled bool
+}
+
+func NewRateLimitMiddleware(limiter *cache.RateLimiter, logger *logrus.Logger, enabled bool) *RateLimitMiddleware {
+	return &RateLimitMiddleware{
+		limiter: limiter,
+		logger:  logger,
+		enabled: enabled,
+	}
+}
+
+// ByIP limits requests from one client address.
+func (h *RateLimitMiddleware) ByIP(group string, quota config.Quota) func(http.Handler) http.Handler {
+	return h.limit(group, quota, func(r *http.Request) string {
+		return "ip:" + ClientIP(r)
+	})
+}
+
+// ByUser limits requests of one authenticated user. It has to run after
+// AuthMiddleware.MakeAuth.
+func (h *RateLimitMiddleware) ByUser(group string, quota config.Quota) func(http.Handler) http.Handler {
+	return h.limit(group, quota, func(r *http.Request) string {
+		tokenInfo, ok := r.Context().Value(TokenInfoKey).(tokens.TokenInfo)
+		if !ok {
+			return ""
+		}
+		return "user:" + tokenInfo.UserID
+	})
+}
+
+func (h *RateLimitMiddleware) limit(group string, quota config.Quota, keyFunc func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !h.enabled {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := keyFunc(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			res, err := h.limiter.Allow(r.Context(), group+":"+key, quota.Limit, quota.Window)
+			if err != nil {
+				// Недоступный Redis не должен класть весь API.
+				h.logger.Warn(err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			resetSeconds := int(math.Ceil(res.Reset.Seconds()))
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(quota.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(resetSeconds))
+
+			if !res.Allowed {
+				response.Error(w, errs.TooManyRequests("rate_limited", "too many requests, try again later", res.Reset))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
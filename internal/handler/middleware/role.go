@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"simple-finance/internal/errs"
+	"simple-finance/internal/handler/response"
+	"simple-finance/internal/tokens"
+)
+
+// RequireRole lets through only users whose token carries one of the roles.
+// It has to run after AuthMiddleware.MakeAuth.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenInfo, ok := r.Context().Value(TokenInfoKey).(tokens.TokenInfo)
+			if !ok {
+				response.InternalServerError(w)
+				return
+			}
+
+			if !slices.Contains(roles, tokenInfo.Role) {
+				response.Error(w, errs.Forbidden("insufficient_role", "you do not have permission to access this resource"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+
+	"simple-finance/internal/errs"
+	"simple-finance/internal/handler/response"
+	"simple-finance/internal/tokens"
+)
+
+// RequireScope limits personal API tokens to the resource they were issued for.
+// Safe methods need read access, everything else needs write access.
+// It has to run after AuthMiddleware.MakeAuth.
+func RequireScope(resource string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenInfo, ok := r.Context().Value(TokenInfoKey).(tokens.TokenInfo)
+			if !ok {
+				response.InternalServerError(w)
+				return
+			}
+
+			write := r.Method != http.MethodGet && r.Method != http.MethodHead
+			if !tokenInfo.Allows(resource, write) {
+				response.Error(w, errs.Forbidden("insufficient_scope", "the api token does not allow this request"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Timeout sets a deadline on the request context. Handlers pass this context
+// down to the database, so queries are cancelled once the deadline passes or
+// the client goes away.
+func Timeout(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// LimitBody caps the request body size. Reading past the limit makes the JSON
+// decoder return an error, which handlers report as a bad request. Paths in
+// overrides get their own limit instead of maxBytes.
+func LimitBody(maxBytes int64, overrides map[string]int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit := maxBytes
+			if override, ok := overrides[r.URL.Path]; ok {
+				limit = override
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+
+	"simple-finance/internal/db"
+	"simple-finance/internal/errs"
+	"simple-finance/internal/handler/response"
+	"simple-finance/internal/tokens"
+)
+
+// VerifiedEmailMiddleware blocks users whose email is not verified yet.
+// It has to run after AuthMiddleware.MakeAuth.
+type VerifiedEmailMiddleware struct {
+	db      *db.FinanceDB
+	enabled bool
+}
+
+func NewVerifiedEmailMiddleware(db *db.FinanceDB, enabled bool) *VerifiedEmailMiddleware {
+	return &VerifiedEmailMiddleware{
+		db:      db,
+		enabled: enabled,
+	}
+}
+
+func (h *VerifiedEmailMiddleware) RequireVerified(next http.Handler) http.Handler {
+	if !h.enabled {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenInfo, ok := r.Context().Value(TokenInfoKey).(tokens.TokenInfo)
+		if !ok {
+			response.InternalServerError(w)
+			return
+		}
+
+		verified, err := h.db.IsEmailVerified(r.Context(), tokenInfo.UserID)
+		if err != nil {
+			response.Error(w, err)
+			return
+		}
+
+		if !verified {
+			response.Error(w, errs.Forbidden("email_not_verified", "email is not verified"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
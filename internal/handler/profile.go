@@ -0,0 +1,382 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"simple-finance/internal/auth"
+	"simple-finance/internal/cache"
+	"simple-finance/internal/db"
+	"simple-finance/internal/errs"
+	"simple-finance/internal/handler/middleware"
+	"simple-finance/internal/handler/response"
+	"simple-finance/internal/models"
+	"simple-finance/internal/tokens"
+	"simple-finance/internal/validation"
+)
+
+type ProfileHandler struct {
+	db           *db.FinanceDB
+	validate     *validation.Validator
+	logger       *logrus.Logger
+	profileCache *cache.ProfileCache
+	authManager  *auth.Manager
+	verifier     *auth.EmailVerifier
+}
+
+func NewProfileHandler(
+	db *db.FinanceDB,
+	validate *validation.Validator,
+	logger *logrus.Logger,
+	profileCache *cache.ProfileCache,
+	authManager *auth.Manager,
+	verifier *auth.EmailVerifier,
+) *ProfileHandler {
+	return &ProfileHandler{
+		db:           db,
+		validate:     validate,
+		logger:       logger,
+		profileCache: profileCache,
+		authManager:  authManager,
+		verifier:     verifier,
+	}
+}
+
+// GetMe             godoc
+// @Summary      Get own profile
+// @Description  Get profile of the authenticated user
+// @Tags         profile
+// @Produce      json
+// @Param        If-None-Match  header  string  false  "ETag of the profile the client already has"
+// @Success      200  {object}  models.UserInfoWithoutPass
+// @Success      304
+// @Failure      401  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/me [get]
+// @Security     Bearer
+func (h *ProfileHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	profile, err := h.loadProfile(r.Context(), tokenInfo.UserID)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	h.writeProfile(w, r, profile)
+}
+
+// GetProfile             godoc
+// @Summary      Get profile
+// @Description  Get profile by its ID. Only admins can see profiles of other users
+// @Tags         profile
+// @Produce      json
+// @Param        id             path    string  true   "id"
+// @Param        If-None-Match  header  string  false  "ETag of the profile the client already has"
+// @Success      200  {object}  models.UserInfoWithoutPass
+// @Success      304
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/profile/{id} [get]
+// @Security     Bearer
+func (h *ProfileHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+	ctx := r.Context()
+
+	if userID != tokenInfo.UserID && tokenInfo.Role != models.RoleAdmin {
+		response.Error(w, errs.Forbidden("admin_only", "only admins can view other profiles"))
+		return
+	}
+
+	profile, err := h.loadProfile(ctx, userID)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	h.writeProfile(w, r, profile)
+}
+
+// UpdateMe             godoc
+// @Summary      Update own profile
+// @Description  Change username and/or email of the authenticated user
+// @Tags         profile
+// @Accept       json
+// @Produce      json
+// @Param        If-Match  header  string                     true  "ETag of the profile, its version in quotes"
+// @Param        input     body    models.UpdateProfileInput  true  "Profile changes"
+// @Success      200    {object}  models.UserInfoWithoutPass
+// @Failure      400    {object}  response.Problem
+// @Failure      401    {object}  response.Problem
+// @Failure      409    {object}  response.Problem
+// @Failure      412    {object}  response.Problem
+// @Failure      422    {object}  response.Problem
+// @Failure      428    {object}  response.Problem
+// @Failure      500    {object}  response.Problem
+// @Router       /api/me [patch]
+// @Security     Bearer
+func (h *ProfileHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	var input models.UpdateProfileInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response.DecodeError(w, err)
+		return
+	}
+
+	err = h.validate.Struct(input, r.Header.Get("Accept-Language"))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	if input.UserName == nil && input.Email == nil {
+		response.BadRequest(w, "nothing to update")
+		return
+	}
+
+	ctx := r.Context()
+	userInfo, err := h.db.UpdateUser(ctx, tokenInfo.UserID, input.UserName, input.Email, version)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	h.invalidateProfile(ctx, tokenInfo.UserID)
+
+	if !userInfo.EmailVerified {
+		err = h.verifier.SendVerification(ctx, userInfo.ID, userInfo.Email)
+		if err != nil {
+			h.logger.Warn(err)
+		}
+	}
+
+	resp, err := json.Marshal(userInfo)
+	if err != nil {
+		h.logger.Warn(err)
+		response.InternalServerError(w)
+		return
+	}
+
+	w.Header().Set("ETag", response.ETag(userInfo.Version))
+	response.WriteResponse(w, http.StatusOK, resp)
+}
+
+// DeleteMe             godoc
+// @Summary      Delete own account
+// @Description  Delete the authenticated user together with transactions, incomes, categories and tags. Contributions to goals of shared ledgers stay without an author
+// @Tags         profile
+// @Accept       json
+// @Produce      json
+// @Param        If-Match  header  string                     true  "ETag of the profile, its version in quotes"
+// @Param        input     body    models.DeleteAccountInput  true  "Current password"
+// @Success      200    {object}  response.IDResponse
+// @Failure      400    {object}  response.Problem
+// @Failure      401    {object}  response.Problem
+// @Failure      412    {object}  response.Problem
+// @Failure      422    {object}  response.Problem
+// @Failure      428    {object}  response.Problem
+// @Failure      500    {object}  response.Problem
+// @Router       /api/me [delete]
+// @Security     Bearer
+func (h *ProfileHandler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	var input models.DeleteAccountInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response.DecodeError(w, err)
+		return
+	}
+
+	err = h.validate.Struct(input, r.Header.Get("Accept-Language"))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	ctx := r.Context()
+	err = h.authManager.CheckPassword(ctx, tokenInfo.UserID, input.Password)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	err = h.db.DeleteUser(ctx, tokenInfo.UserID, version)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	// Пользователя уже нет, но выданные токены ещё не истекли.
+	err = h.authManager.RevokeTokens(ctx, tokenInfo.UserID)
+	if err != nil {
+		h.logger.Warn(err)
+	}
+	h.invalidateProfile(ctx, tokenInfo.UserID)
+
+	response.IdResponse(w, tokenInfo.UserID)
+}
+
+// ResendVerification             godoc
+// @Summary      Resend verification email
+// @Description  Send a new email verification link to the authenticated user
+// @Tags         profile
+// @Produce      json
+// @Success      200  {object}  response.Body
+// @Failure      401  {object}  response.Problem
+// @Failure      409  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/me/verify_email [post]
+// @Security     Bearer
+func (h *ProfileHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	ctx := r.Context()
+	userInfo, err := h.db.GetUserById(ctx, tokenInfo.UserID)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	if userInfo.EmailVerified {
+		response.Error(w, errs.Conflict("email_already_verified", "email is already verified"))
+		return
+	}
+
+	err = h.verifier.SendVerification(ctx, userInfo.ID, userInfo.Email)
+	if err != nil {
+		h.logger.Warn(err)
+		response.InternalServerError(w)
+		return
+	}
+
+	response.OKMessage(w, "verification email sent")
+}
+
+// VerifyEmail             godoc
+// @Summary      Verify email
+// @Description  Confirm email with the token from the verification link
+// @Tags         auth
+// @Produce      json
+// @Param        token  query  string  true  "Verification token"
+// @Success      200  {object}  response.Body
+// @Failure      400  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /auth/verify_email [get]
+func (h *ProfileHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		response.BadRequest(w, "token is empty")
+		return
+	}
+
+	ctx := r.Context()
+	userID, err := h.verifier.Verify(ctx, token)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	h.invalidateProfile(ctx, userID)
+
+	response.OKMessage(w, "email verified")
+}
+
+// loadProfile returns the JSON-encoded profile, from cache when possible.
+// The cache holds exactly the bytes sent to the client, so hits and misses
+// return the same payload.
+func (h *ProfileHandler) loadProfile(ctx context.Context, userID string) ([]byte, error) {
+	cachedData, ok, err := h.profileCache.Get(ctx, userID)
+	if err != nil {
+		// Кеш недоступен — отдаём данные из базы.
+		h.logger.Warn(err)
+	}
+	if ok {
+		return cachedData, nil
+	}
+
+	userInfo, err := h.db.GetUserById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(userInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.profileCache.Set(ctx, userID, data)
+	if err != nil {
+		h.logger.Warn(err)
+	}
+
+	return data, nil
+}
+
+func (h *ProfileHandler) writeProfile(w http.ResponseWriter, r *http.Request, profile []byte) {
+	etag, err := profileETag(profile)
+	if err != nil {
+		h.logger.Warn(err)
+		response.InternalServerError(w)
+		return
+	}
+
+	response.WriteWithETag(w, r, etag, profile)
+}
+
+func (h *ProfileHandler) invalidateProfile(ctx context.Context, userID string) {
+	err := h.profileCache.Invalidate(ctx, userID)
+	if err != nil {
+		h.logger.Warn(err)
+	}
+}
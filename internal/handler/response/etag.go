@@ -0,0 +1,48 @@
+package response
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ETag formats the version of a single resource as an entity tag. Clients
+// send it back in If-Match when they change the resource.
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ContentETag derives an entity tag from the body. It suits lists, which have
+// no version of their own.
+func ContentETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// WriteWithETag writes the body with the ETag header, or 304 Not Modified
+// when If-None-Match shows the client already has this representation.
+func WriteWithETag(w http.ResponseWriter, r *http.Request, etag string, body []byte) {
+	w.Header().Set("ETag", etag)
+
+	if noneMatch := r.Header.Get("If-None-Match"); noneMatch != "" && matchesAny(noneMatch, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	WriteResponse(w, http.StatusOK, body)
+}
+
+// matchesAny compares the tag with a header list using the weak comparison
+// that RFC 9110 prescribes for If-None-Match.
+func matchesAny(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,164 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"simple-finance/internal/errs"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem represents an error response in RFC 7807 format
+// @Description  Error response (RFC 7807 problem details)
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Code   string `json:"code"`
+
+	Errors []errs.FieldError `json:"errors,omitempty"`
+}
+
+// Стабильные коды ошибок, которые не привязаны к конкретному ресурсу.
+const (
+	CodeBadRequest    = "bad_request"
+	CodeUnauthorized  = "unauthorized"
+	CodeNotFound      = "not_found"
+	CodeInternal      = "internal_error"
+	CodeTimeout       = "timeout"
+	CodeRequestTooBig = "request_too_large"
+	CodeInvalidJSON   = "invalid_json"
+
+	CodePreconditionRequired = "precondition_required"
+)
+
+func WriteProblem(w http.ResponseWriter, status int, code, detail string) {
+	writeProblem(w, status, code, detail, nil)
+}
+
+func writeProblem(w http.ResponseWriter, status int, code, detail string, fields []errs.FieldError) {
+	problem := Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+		Errors: fields,
+	}
+
+	body, err := json.Marshal(problem)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Printf("error occurred when marshalling problem: %v\n", problem)
+		return
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(status)
+
+	_, err = w.Write(body)
+	if err != nil {
+		log.Printf("error occurred when write problem: %v", problem)
+	}
+}
+
+// DecodeError reports a request body that could not be decoded.
+func DecodeError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		WriteProblem(w, http.StatusRequestEntityTooLarge, CodeRequestTooBig, "request body is too large")
+		return
+	}
+
+	// Не показываем клиенту имена Go-типов, только поле и ожидаемый тип.
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		expected := jsonType(typeErr.Type)
+		writeProblem(w, http.StatusBadRequest, CodeInvalidJSON, "request body has fields of wrong type", []errs.FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Param:   expected,
+			Message: typeErr.Field + " must be " + expected,
+		}})
+		return
+	}
+
+	WriteProblem(w, http.StatusBadRequest, CodeInvalidJSON, "request body is not valid JSON")
+}
+
+// Error renders err as problem+json. Domain errors from the errs package are
+// shown with their own code and message, anything else becomes a generic 500
+// so internal details never leak to the client.
+func Error(w http.ResponseWriter, err error) {
+	var domainErr *errs.Error
+	if errors.As(err, &domainErr) {
+		if domainErr.RetryAfter > 0 {
+			SetRetryAfter(w, domainErr.RetryAfter)
+		}
+		writeProblem(w, statusOf(domainErr.Kind), domainErr.Code, domainErr.Message, domainErr.Fields)
+		return
+	}
+
+	switch {
+	case errors.Is(err, errs.ErrInvalidPassword), errors.Is(err, errs.ErrInvalidToken):
+		Unauthorized(w)
+	case errors.Is(err, context.DeadlineExceeded):
+		WriteProblem(w, http.StatusGatewayTimeout, CodeTimeout, "request took too long")
+	case errors.Is(err, context.Canceled):
+		// Клиент уже отключился, ответ никто не прочитает.
+		w.WriteHeader(http.StatusRequestTimeout)
+	default:
+		InternalServerError(w)
+	}
+}
+
+func statusOf(kind error) int {
+	switch {
+	case errors.Is(kind, errs.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(kind, errs.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(kind, errs.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(kind, errs.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(kind, errs.ErrTooMany):
+		return http.StatusTooManyRequests
+	case errors.Is(kind, errs.ErrPrecondition):
+		return http.StatusPreconditionFailed
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map:
+		return "object"
+	default:
+		// time.Time и прочие типы с собственным форматом приходят строкой.
+		return "string"
+	}
+}
+
+// SetRetryAfter sets the Retry-After header rounding d up to whole seconds.
+func SetRetryAfter(w http.ResponseWriter, d time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+}
@@ -27,6 +27,13 @@ func WriteResponse(w http.ResponseWriter, status int, body []byte) {
 	}
 }
 
+// WriteNoStore writes a response with a secret that is shown only once, so
+// neither HTTP caches nor the idempotency store may keep it.
+func WriteNoStore(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Cache-Control", "no-store")
+	WriteResponse(w, status, body)
+}
+
 func WriteMessage(w http.ResponseWriter, status int, msg string) {
 	respBody := Body{Message: msg}
 	body, err := json.Marshal(respBody)
@@ -40,11 +47,11 @@ func WriteMessage(w http.ResponseWriter, status int, msg string) {
 }
 
 func BadRequest(w http.ResponseWriter, text string) {
-	WriteMessage(w, http.StatusBadRequest, text)
+	WriteProblem(w, http.StatusBadRequest, CodeBadRequest, text)
 }
 
 func NotFound(w http.ResponseWriter, text string) {
-	WriteMessage(w, http.StatusNotFound, text)
+	WriteProblem(w, http.StatusNotFound, CodeNotFound, text)
 }
 
 func OKMessage(w http.ResponseWriter, text string) {
@@ -52,12 +59,12 @@ func OKMessage(w http.ResponseWriter, text string) {
 }
 
 func InternalServerError(w http.ResponseWriter) {
-	WriteMessage(w, http.StatusInternalServerError, "Internal server error")
+	WriteProblem(w, http.StatusInternalServerError, CodeInternal, "Internal server error")
 }
 
 func Unauthorized(w http.ResponseWriter) {
 	w.Header().Set("WWW-Authenticate", `Basic realm="restricted", charset="UTF-8"`)
-	WriteMessage(w, http.StatusUnauthorized, "Unauthorized")
+	WriteProblem(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
 }
 
 func IdResponse(w http.ResponseWriter, id string) {
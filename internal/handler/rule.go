@@ -0,0 +1,277 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"simple-finance/internal/handler/middleware"
+	"simple-finance/internal/handler/response"
+	"simple-finance/internal/models"
+	"simple-finance/internal/rules"
+	"simple-finance/internal/tokens"
+)
+
+// GetRules             godoc
+// @Summary      List rules
+// @Description  List auto-categorisation rules of the ledger in the order they are checked
+// @Tags         rules
+// @Produce      json
+// @Param        ledger_id      path    string  true   "Ledger ID"
+// @Param        If-None-Match  header  string  false  "ETag of the list the client already has"
+// @Success      200  {array}   models.Rule
+// @Success      304
+// @Failure      401  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/rules [get]
+// @Security     Bearer
+func (h *LedgerHandler) GetRules(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	list, err := h.db.GetRules(r.Context(), tokenInfo.UserID, chi.URLParam(r, "ledger_id"))
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	h.writeList(w, r, list)
+}
+
+// CreateRule             godoc
+// @Summary      Create rule
+// @Description  Add a rule that assigns a category and tags to new transactions of the ledger. Requires the owner or editor role
+// @Tags         rules
+// @Accept       json
+// @Produce      json
+// @Param        ledger_id  path  string            true  "Ledger ID"
+// @Param        input      body  models.RuleInput  true  "Rule"
+// @Success      200  {object}  models.Rule
+// @Failure      400  {object}  response.Problem
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/rules [post]
+// @Security     Bearer
+func (h *LedgerHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	rule, ok := h.decodeRule(w, r)
+	if !ok {
+		return
+	}
+
+	rule.ID = uuid.New().String()
+
+	rule, err := h.db.InsertRule(r.Context(), tokenInfo.UserID, rule)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	h.writeRule(w, rule)
+}
+
+// UpdateRule             godoc
+// @Summary      Replace rule
+// @Description  Replace the rule definition. Its tags become yours. Requires the owner or editor role
+// @Tags         rules
+// @Accept       json
+// @Produce      json
+// @Param        ledger_id  path    string            true  "Ledger ID"
+// @Param        rule_id    path    string            true  "Rule ID"
+// @Param        If-Match   header  string            true  "ETag of the rule, its version in quotes"
+// @Param        input      body    models.RuleInput  true  "Rule"
+// @Success      200  {object}  models.Rule
+// @Failure      400  {object}  response.Problem
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      412  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      428  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/rules/{rule_id} [put]
+// @Security     Bearer
+func (h *LedgerHandler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	rule, ok := h.decodeRule(w, r)
+	if !ok {
+		return
+	}
+
+	rule.ID = chi.URLParam(r, "rule_id")
+
+	rule, err := h.db.UpdateRule(r.Context(), tokenInfo.UserID, rule, version)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	h.writeRule(w, rule)
+}
+
+// DeleteRule             godoc
+// @Summary      Delete rule
+// @Description  Delete the rule. Transactions it has changed stay as they are. Requires the owner or editor role
+// @Tags         rules
+// @Produce      json
+// @Param        ledger_id  path    string  true  "Ledger ID"
+// @Param        rule_id    path    string  true  "Rule ID"
+// @Param        If-Match   header  string  true  "ETag of the rule, its version in quotes"
+// @Success      200  {object}  response.IDResponse
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      412  {object}  response.Problem
+// @Failure      428  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/rules/{rule_id} [delete]
+// @Security     Bearer
+func (h *LedgerHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	ruleID := chi.URLParam(r, "rule_id")
+
+	err := h.db.DeleteRule(r.Context(), tokenInfo.UserID, chi.URLParam(r, "ledger_id"), ruleID, version)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	response.IdResponse(w, ruleID)
+}
+
+// TestRule             godoc
+// @Summary      Test rule
+// @Description  Dry-run a rule definition against live transactions of the ledger without changing them
+// @Tags         rules
+// @Accept       json
+// @Produce      json
+// @Param        ledger_id  path  string            true  "Ledger ID"
+// @Param        input      body  models.RuleInput  true  "Rule"
+// @Success      200  {object}  models.RuleTestResult
+// @Failure      400  {object}  response.Problem
+// @Failure      401  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/rules/test [post]
+// @Security     Bearer
+func (h *LedgerHandler) TestRule(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	rule, ok := h.decodeRule(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.db.TestRule(r.Context(), tokenInfo.UserID, rule)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	h.writeJSON(w, result)
+}
+
+// ApplyRules             godoc
+// @Summary      Re-apply rules
+// @Description  Run enabled rules of the ledger over its live transactions, at most 5000 at a time. The rule's category replaces the current one. Requires the owner or editor role
+// @Tags         rules
+// @Accept       json
+// @Produce      json
+// @Param        ledger_id  path  string                  true  "Ledger ID"
+// @Param        input      body  models.ApplyRulesInput  true  "Date range, {} for all transactions"
+// @Success      200  {object}  models.RuleApplyResult
+// @Failure      400  {object}  response.Problem
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/ledgers/{ledger_id}/rules/apply [post]
+// @Security     Bearer
+func (h *LedgerHandler) ApplyRules(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	var input models.ApplyRulesInput
+	if !h.decode(w, r, &input) {
+		return
+	}
+
+	result, err := h.db.ApplyRules(r.Context(), tokenInfo.UserID, chi.URLParam(r, "ledger_id"), input)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	h.writeJSON(w, result)
+}
+
+// decodeRule reads and checks a rule definition for the ledger from the URL.
+func (h *LedgerHandler) decodeRule(w http.ResponseWriter, r *http.Request) (models.Rule, bool) {
+	var input models.RuleInput
+	if !h.decode(w, r, &input) {
+		return models.Rule{}, false
+	}
+
+	err := rules.Validate(input)
+	if err != nil {
+		response.Error(w, err)
+		return models.Rule{}, false
+	}
+
+	rule := rules.FromInput(input)
+	rule.LedgerID = chi.URLParam(r, "ledger_id")
+
+	return rule, true
+}
+
+func (h *LedgerHandler) writeRule(w http.ResponseWriter, rule models.Rule) {
+	w.Header().Set("ETag", response.ETag(rule.Version))
+	h.writeJSON(w, rule)
+}
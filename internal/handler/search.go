@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"simple-finance/internal/handler/middleware"
+	"simple-finance/internal/handler/response"
+	"simple-finance/internal/models"
+	"simple-finance/internal/tokens"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	maxSearchOffset    = 10000
+	maxSearchQuery     = 200
+
+	searchDateLayout = "2006-01-02"
+)
+
+// Search             godoc
+// @Summary      Search transactions
+// @Description  Full-text and fuzzy search over comments, category names and your tag names in all your ledgers or in one of them. Most relevant first
+// @Tags         transactions
+// @Produce      json
+// @Param        q          query  string  true   "Search text, supports quotes, OR and -word"
+// @Param        ledger_id  query  string  false  "Ledger ID, all ledgers by default"
+// @Param        date_from  query  string  false  "First date, YYYY-MM-DD"
+// @Param        date_to    query  string  false  "Last date, YYYY-MM-DD"
+// @Param        limit      query  int     false  "Page size, 20 by default, at most 100"
+// @Param        offset     query  int     false  "Number of results to skip"
+// @Success      200  {array}   models.SearchResult
+// @Failure      400  {object}  response.Problem
+// @Failure      401  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/search [get]
+// @Security     Bearer
+func (h *TransactionHandler) Search(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	query := r.URL.Query()
+
+	filter := models.SearchFilter{
+		Query:    strings.TrimSpace(query.Get("q")),
+		LedgerID: query.Get("ledger_id"),
+		Limit:    defaultSearchLimit,
+	}
+
+	if filter.Query == "" || utf8.RuneCountInString(filter.Query) > maxSearchQuery {
+		response.BadRequest(w, "q must be between 1 and 200 characters")
+		return
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > maxSearchLimit {
+			response.BadRequest(w, "limit must be between 1 and 100")
+			return
+		}
+		filter.Limit = value
+	}
+
+	// Глубокие страницы по релевантности никому не нужны, а стоят дорого.
+	if offset := query.Get("offset"); offset != "" {
+		value, err := strconv.Atoi(offset)
+		if err != nil || value < 0 || value > maxSearchOffset {
+			response.BadRequest(w, "offset must be between 0 and 10000")
+			return
+		}
+		filter.Offset = value
+	}
+
+	var err error
+
+	filter.DateFrom, err = parseDateParam(query.Get("date_from"))
+	if err != nil {
+		response.BadRequest(w, "date_from must be a date in YYYY-MM-DD format")
+		return
+	}
+
+	filter.DateTo, err = parseDateParam(query.Get("date_to"))
+	if err != nil {
+		response.BadRequest(w, "date_to must be a date in YYYY-MM-DD format")
+		return
+	}
+
+	results, err := h.db.SearchTransactions(r.Context(), tokenInfo.UserID, filter)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(results)
+	if err != nil {
+		h.logger.Warn(err)
+		response.InternalServerError(w)
+		return
+	}
+
+	response.WriteResponse(w, http.StatusOK, resp)
+}
+
+// parseDateParam returns nil for an empty query parameter.
+func parseDateParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	date, err := time.Parse(searchDateLayout, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &date, nil
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"simple-finance/internal/handler/middleware"
+	"simple-finance/internal/handler/response"
+	"simple-finance/internal/models"
+	"simple-finance/internal/tokens"
+)
+
+// Размеры колонок в таблице sessions.
+const (
+	maxUserAgentLength = 512
+	maxIPLength        = 45
+)
+
+// GetSessions             godoc
+// @Summary      List sessions
+// @Description  List devices where the user is signed in. The session of the request is marked as current
+// @Tags         sessions
+// @Produce      json
+// @Success      200  {array}   models.Session
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/sessions [get]
+// @Security     Bearer
+func (h *AuthHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	sessions, err := h.authManager.Sessions(r.Context(), tokenInfo.UserID)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == tokenInfo.SessionID
+	}
+
+	ansBytes, err := json.Marshal(sessions)
+	if err != nil {
+		h.logger.Warn(err)
+		response.InternalServerError(w)
+		return
+	}
+
+	response.WriteResponse(w, http.StatusOK, ansBytes)
+}
+
+// RevokeSession             godoc
+// @Summary      Revoke session
+// @Description  Sign out a device. Its access and refresh tokens stop working immediately
+// @Tags         sessions
+// @Produce      json
+// @Param        session_id  path  string  true  "Session ID"
+// @Success      200  {object}  response.IDResponse
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/sessions/{session_id} [delete]
+// @Security     Bearer
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	sessionID := chi.URLParam(r, "session_id")
+
+	err := h.authManager.RevokeSession(r.Context(), tokenInfo.UserID, sessionID)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	response.IdResponse(w, sessionID)
+}
+
+// clientInfo describes the device making the request.
+func clientInfo(r *http.Request) models.Session {
+	return models.Session{
+		UserAgent: truncate(r.UserAgent(), maxUserAgentLength),
+		IP:        truncate(middleware.ClientIP(r), maxIPLength),
+	}
+}
+
+// truncate cuts s to at most n bytes and drops invalid UTF-8 that clients
+// may send in headers and the cut may leave.
+func truncate(s string, n int) string {
+	if len(s) > n {
+		s = s[:n]
+	}
+
+	return strings.ToValidUTF8(s, "")
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"simple-finance/internal/handler/middleware"
+	"simple-finance/internal/handler/response"
+	"simple-finance/internal/tokens"
+)
+
+const (
+	defaultSuggestLimit = 3
+	maxSuggestLimit     = 10
+	maxSuggestComment   = 1000
+)
+
+// SuggestCategories             godoc
+// @Summary      Suggest categories
+// @Description  Rank categories of the ledger for a new transaction, learned from transactions you have categorised in it
+// @Tags         transactions
+// @Produce      json
+// @Param        comment    query  string  true   "Transaction comment"
+// @Param        amount     query  number  false  "Transaction amount"
+// @Param        ledger_id  query  string  false  "Ledger ID, the personal ledger by default"
+// @Param        limit      query  int     false  "Number of suggestions, 3 by default, at most 10"
+// @Success      200  {array}   models.CategorySuggestion
+// @Failure      400  {object}  response.Problem
+// @Failure      401  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/transaction/suggest [get]
+// @Security     Bearer
+func (h *TransactionHandler) SuggestCategories(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	query := r.URL.Query()
+
+	comment := strings.TrimSpace(query.Get("comment"))
+	if comment == "" || len(comment) > maxSuggestComment {
+		response.BadRequest(w, "comment must be between 1 and 1000 characters")
+		return
+	}
+
+	var amount float64
+	if value := query.Get("amount"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed <= 0 {
+			response.BadRequest(w, "amount must be a positive number")
+			return
+		}
+		amount = parsed
+	}
+
+	limit := defaultSuggestLimit
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxSuggestLimit {
+			response.BadRequest(w, "limit must be between 1 and 10")
+			return
+		}
+		limit = parsed
+	}
+
+	ledgerID := query.Get("ledger_id")
+	if ledgerID == "" {
+		ledgerID = tokenInfo.UserID
+	}
+
+	suggestions, err := h.db.SuggestCategories(r.Context(), tokenInfo.UserID, ledgerID, comment, amount, limit)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(suggestions)
+	if err != nil {
+		h.logger.Warn(err)
+		response.InternalServerError(w)
+		return
+	}
+
+	response.WriteResponse(w, http.StatusOK, resp)
+}
@@ -1,11 +1,8 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"github.com/go-chi/chi/v5"
-	"github.com/go-playground/validator/v10"
-	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"net/http"
@@ -14,41 +11,41 @@ import (
 	"simple-finance/internal/handler/response"
 	"simple-finance/internal/models"
 	"simple-finance/internal/tokens"
-	"time"
+	"simple-finance/internal/validation"
 )
 
 type TransactionHandler struct {
-	db          *db.FinanceDB
-	validator   *validator.Validate
-	logger      *logrus.Logger
-	redisClient *redis.Client
+	db        *db.FinanceDB
+	validator *validation.Validator
+	logger    *logrus.Logger
 }
 
 func NewTransactionHandler(
 	db *db.FinanceDB,
-	validator *validator.Validate,
+	validator *validation.Validator,
 	logger *logrus.Logger,
-	redisClient *redis.Client,
 ) *TransactionHandler {
 	return &TransactionHandler{
-		db:          db,
-		validator:   validator,
-		logger:      logger,
-		redisClient: redisClient,
+		db:        db,
+		validator: validator,
+		logger:    logger,
 	}
 }
 
 // InsertTransaction             godoc
 // @Summary      Create a new transaction
-// @Description  Add a new financial transaction for the authenticated user
+// @Description  Add a new financial transaction to a ledger, the personal one by default. Requires the owner or editor role
 // @Tags         transactions
 // @Accept       json
 // @Produce      json
 // @Param        input  body  models.Transaction  true  "Transaction data"
 // @Success      200    {object}  response.IDResponse
-// @Failure      400    {object}  string
-// @Failure      401    {object}  string
-// @Failure      500    {object}  string
+// @Failure      400    {object}  response.Problem
+// @Failure      401    {object}  response.Problem
+// @Failure      403    {object}  response.Problem
+// @Failure      404    {object}  response.Problem
+// @Failure      422    {object}  response.Problem
+// @Failure      500    {object}  response.Problem
 // @Router       /api/transaction [post]
 // @Security Bearer
 func (h *TransactionHandler) InsertTransaction(w http.ResponseWriter, r *http.Request) {
@@ -62,25 +59,28 @@ func (h *TransactionHandler) InsertTransaction(w http.ResponseWriter, r *http.Re
 	err := json.NewDecoder(r.Body).Decode(&transaction)
 
 	if err != nil {
-		response.BadRequest(w, err.Error())
+		response.DecodeError(w, err)
 		return
 	}
 
-	err = h.validator.Struct(transaction)
+	err = h.validator.Struct(transaction, r.Header.Get("Accept-Language"))
 	if err != nil {
-		response.BadRequest(w, err.Error())
+		response.Error(w, err)
 		return
 	}
 
 	transaction.UserID = tokenInfo.UserID
+	if transaction.LedgerID == "" {
+		transaction.LedgerID = tokenInfo.UserID
+	}
 	id := uuid.New().String()
 	transaction.ID = id
-	ctx := context.Background()
+	ctx := r.Context()
 	transactionID, err := h.db.InsertTransaction(ctx, transaction)
 
 	if err != nil {
 		h.logger.Warn(err)
-		response.InternalServerError(w)
+		response.Error(w, err)
 		return
 	}
 
@@ -89,12 +89,17 @@ func (h *TransactionHandler) InsertTransaction(w http.ResponseWriter, r *http.Re
 
 // GetTransactions             godoc
 // @Summary      Get user transactions
-// @Description  Retrieve all transactions for the authenticated user
+// @Description  Retrieve all transactions of a ledger, the personal one by default
 // @Tags         transactions
 // @Produce      json
+// @Param        ledger_id      query   string  false  "Ledger ID"
+// @Param        If-None-Match  header  string  false  "ETag of the list the client already has"
 // @Success      200  {array}  models.Transaction
-// @Failure      401  {object}  string
-// @Failure      500  {object}  string
+// @Success      304
+// @Failure      401  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
 // @Router       /api/transaction [get]
 // @Security     Bearer
 func (h *TransactionHandler) GetTransactions(w http.ResponseWriter, r *http.Request) {
@@ -105,11 +110,16 @@ func (h *TransactionHandler) GetTransactions(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	ctx := context.Background()
-	transactions, err := h.db.GetTransactions(ctx, tokenInfo.UserID)
+	ledgerID := r.URL.Query().Get("ledger_id")
+	if ledgerID == "" {
+		ledgerID = tokenInfo.UserID
+	}
+
+	ctx := r.Context()
+	transactions, err := h.db.GetTransactions(ctx, tokenInfo.UserID, ledgerID)
 	if err != nil {
 		h.logger.Warn(err)
-		response.InternalServerError(w)
+		response.Error(w, err)
 		return
 	}
 
@@ -119,7 +129,7 @@ func (h *TransactionHandler) GetTransactions(w http.ResponseWriter, r *http.Requ
 		response.InternalServerError(w)
 		return
 	}
-	response.WriteResponse(w, http.StatusOK, resp)
+	response.WriteWithETag(w, r, response.ContentETag(resp), resp)
 }
 
 // GetTransactionByID             godoc
@@ -127,11 +137,15 @@ func (h *TransactionHandler) GetTransactions(w http.ResponseWriter, r *http.Requ
 // @Description  Get a specific transaction by its ID
 // @Tags         transactions
 // @Produce      json
-// @Param        transaction_uuid  path  string  true  "Transaction UUID"
+// @Param        transaction_uuid  path    string  true   "Transaction UUID"
+// @Param        If-None-Match     header  string  false  "ETag of the transaction the client already has"
 // @Success      200  {object}  models.Transaction
-// @Failure      400  {object}  string
-// @Failure      401  {object}  string
-// @Failure      500  {object}  string
+// @Success      304
+// @Failure      400  {object}  response.Problem
+// @Failure      401  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
 // @Router       /api/transaction/{transaction_uuid} [get]
 // @Security Bearer
 func (h *TransactionHandler) GetTransactionByID(w http.ResponseWriter, r *http.Request) {
@@ -147,10 +161,10 @@ func (h *TransactionHandler) GetTransactionByID(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	transaction, err := h.db.GetTransactionByID(context.Background(), tokenInfo.UserID, transactionID)
+	transaction, err := h.db.GetTransactionByID(r.Context(), tokenInfo.UserID, transactionID)
 	if err != nil {
 		h.logger.Warn(err)
-		response.InternalServerError(w)
+		response.Error(w, err)
 		return
 	}
 
@@ -161,84 +175,121 @@ func (h *TransactionHandler) GetTransactionByID(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	response.WriteResponse(w, http.StatusOK, resp)
+	response.WriteWithETag(w, r, response.ETag(transaction.Version), resp)
 }
 
-// DeleteTransactionByID             godoc
-// @Summary      Delete transaction
-// @Description  Delete a specific transaction by its ID
+// UpdateTransaction             godoc
+// @Summary      Update transaction
+// @Description  Change amount, category, comment or date of a transaction. Requires the owner or editor role in its ledger
 // @Tags         transactions
+// @Accept       json
 // @Produce      json
-// @Param        transaction_uuid  path  string  true  "Transaction UUID"
-// @Success      200
-// @Failure      400  {object}  string
-// @Failure      401  {object}  string
-// @Failure      404  {object}  string
-// @Failure      500  {object}  string
-// @Router       /api/transaction/{transaction_uuid} [delete]
+// @Param        transaction_uuid  path    string                         true  "Transaction UUID"
+// @Param        If-Match          header  string                         true  "ETag of the transaction, its version in quotes"
+// @Param        input             body    models.UpdateTransactionInput  true  "Transaction changes"
+// @Success      200  {object}  models.Transaction
+// @Failure      400  {object}  response.Problem
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      412  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      428  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/transaction/{transaction_uuid} [patch]
 // @Security     Bearer
-func (h *TransactionHandler) DeleteTransactionByID(w http.ResponseWriter, r *http.Request) {
+func (h *TransactionHandler) UpdateTransaction(w http.ResponseWriter, r *http.Request) {
 	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
 	if !ok {
 		response.InternalServerError(w)
 		return
 	}
 
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	var input models.UpdateTransactionInput
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		response.DecodeError(w, err)
+		return
+	}
+
+	err = h.validator.Struct(input, r.Header.Get("Accept-Language"))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	if input.Amount == nil && input.CategoryID == nil && input.Comment == nil && input.Date == nil {
+		response.BadRequest(w, "nothing to update")
+		return
+	}
+
 	transactionID := chi.URLParam(r, "transaction_uuid")
-	if transactionID == "" {
-		response.BadRequest(w, "transaction_uuid is empty")
+
+	transaction, err := h.db.UpdateTransaction(r.Context(), tokenInfo.UserID, transactionID, input, version)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
 		return
 	}
 
-	err := h.db.DeleteTransactionByID(context.Background(), tokenInfo.UserID, transactionID)
+	resp, err := json.Marshal(transaction)
 	if err != nil {
 		h.logger.Warn(err)
 		response.InternalServerError(w)
 		return
 	}
 
-	response.IdResponse(w, transactionID)
+	w.Header().Set("ETag", response.ETag(transaction.Version))
+	response.WriteResponse(w, http.StatusOK, resp)
 }
 
-// GetProfileHandler             godoc
-// @Summary      Get profile
-// @Description  Get profile by its ID
+// DeleteTransactionByID             godoc
+// @Summary      Delete transaction
+// @Description  Move a specific transaction to the trash. Requires the owner or editor role in its ledger
 // @Tags         transactions
 // @Produce      json
-// @Param        id  path  string  true  "id"
+// @Param        transaction_uuid  path    string  true  "Transaction UUID"
+// @Param        If-Match          header  string  true  "ETag of the transaction, its version in quotes"
 // @Success      200
-// @Failure      400  {object}  string
-// @Failure      401  {object}  string
-// @Failure      404  {object}  string
-// @Failure      500  {object}  string
-// @Router       /api/profile/{id} [get]
+// @Failure      400  {object}  response.Problem
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      412  {object}  response.Problem
+// @Failure      428  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/transaction/{transaction_uuid} [delete]
 // @Security     Bearer
-func (r *TransactionHandler) GetProfileHandler(w http.ResponseWriter, req *http.Request) {
-	userID := chi.URLParam(req, "id")
-	ctx := req.Context()
-
-	//// Попытка получить данные из кеша
-	cachedData, err := r.redisClient.Get(ctx, "profile:"+userID).Bytes()
-	if err == nil {
-		r.logger.Infof("Got profile from cache: %s", string(cachedData))
+func (h *TransactionHandler) DeleteTransactionByID(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
 
-		w.Header().Set("Content-Type", "application/json")
-		//w.Write([]byte("from redis"))
-		json.NewEncoder(w).Encode(cachedData)
+	transactionID := chi.URLParam(r, "transaction_uuid")
+	if transactionID == "" {
+		response.BadRequest(w, "transaction_uuid is empty")
+		return
+	}
 
+	version, ok := ifMatchVersion(w, r)
+	if !ok {
 		return
 	}
 
-	userInfo, err := r.db.GetUserById(ctx, userID)
+	err := h.db.DeleteTransactionByID(r.Context(), tokenInfo.UserID, transactionID, version)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.logger.Warn(err)
+		response.Error(w, err)
 		return
 	}
-	responseData, _ := json.Marshal(userInfo)
-
-	// Сохраняем в кеш на 10 минут
-	r.redisClient.Set(ctx, "profile:"+userID, responseData, 10*time.Minute)
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(responseData)
+	response.IdResponse(w, transactionID)
 }
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"simple-finance/internal/handler/middleware"
+	"simple-finance/internal/handler/response"
+	"simple-finance/internal/tokens"
+)
+
+// GetTrash             godoc
+// @Summary      List trash
+// @Description  List deleted transactions, categories and goal contributions of a ledger, the personal one by default. They are purged after the retention period
+// @Tags         trash
+// @Produce      json
+// @Param        ledger_id      query   string  false  "Ledger ID"
+// @Param        If-None-Match  header  string  false  "ETag of the trash the client already has"
+// @Success      200  {object}  models.Trash
+// @Success      304
+// @Failure      401  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      422  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/trash [get]
+// @Security     Bearer
+func (h *TransactionHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	ledgerID := r.URL.Query().Get("ledger_id")
+	if ledgerID == "" {
+		ledgerID = tokenInfo.UserID
+	}
+
+	trash, err := h.db.GetTrash(r.Context(), tokenInfo.UserID, ledgerID)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(trash)
+	if err != nil {
+		h.logger.Warn(err)
+		response.InternalServerError(w)
+		return
+	}
+
+	response.WriteWithETag(w, r, response.ContentETag(resp), resp)
+}
+
+// RestoreTransaction             godoc
+// @Summary      Restore transaction
+// @Description  Return a deleted transaction from the trash. Requires the owner or editor role in its ledger
+// @Tags         trash
+// @Produce      json
+// @Param        transaction_uuid  path  string  true  "Transaction UUID"
+// @Success      200  {object}  response.IDResponse
+// @Failure      401  {object}  response.Problem
+// @Failure      403  {object}  response.Problem
+// @Failure      404  {object}  response.Problem
+// @Failure      409  {object}  response.Problem
+// @Failure      500  {object}  response.Problem
+// @Router       /api/transaction/{transaction_uuid}/restore [post]
+// @Security     Bearer
+func (h *TransactionHandler) RestoreTransaction(w http.ResponseWriter, r *http.Request) {
+	tokenInfo, ok := r.Context().Value(middleware.TokenInfoKey).(tokens.TokenInfo)
+	if !ok {
+		response.InternalServerError(w)
+		return
+	}
+
+	transactionID := chi.URLParam(r, "transaction_uuid")
+
+	err := h.db.RestoreTransaction(r.Context(), tokenInfo.UserID, transactionID)
+	if err != nil {
+		h.logger.Warn(err)
+		response.Error(w, err)
+		return
+	}
+
+	response.IdResponse(w, transactionID)
+}
@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+)
+
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from *mail.Address
+}
+
+func NewSMTPMailer(host, port, user, password string, from *mail.Address) *SMTPMailer {
+	var auth smtp.Auth
+	if user != "" {
+		auth = smtp.PlainAuth("", user, password, host)
+	}
+
+	return &SMTPMailer{
+		addr: net.JoinHostPort(host, port),
+		auth: auth,
+		from: from,
+	}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	// net/smtp не умеет работать с контекстом, проверяем его хотя бы перед отправкой.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// В MAIL FROM идёт только сам адрес, имя отправителя остаётся в заголовке From.
+	err := smtp.SendMail(m.addr, m.auth, m.from.Address, []string{msg.To}, format(m.from.String(), msg))
+	if err != nil {
+		return fmt.Errorf("send mail to %s: %w", msg.To, err)
+	}
+
+	return nil
+}
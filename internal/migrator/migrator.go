@@ -0,0 +1,293 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// advisoryLockKey защищает от одновременного запуска миграций несколькими репликами.
+const advisoryLockKey int64 = 7241020113
+
+// Таблица совместима с golang-migrate, поэтому базы, которые раньше
+// мигрировались через CLI из Makefile, продолжают работать.
+const createVersionTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT NOT NULL PRIMARY KEY,
+		dirty BOOLEAN NOT NULL
+	)
+`
+
+var fileNameRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+var ErrDirty = errors.New("database is in dirty state, fix it manually and force the version")
+
+type migration struct {
+	version uint64
+	name    string
+	up      string
+	down    string
+}
+
+// Status describes a single migration and whether it has been applied.
+type Status struct {
+	Version uint64
+	Name    string
+	Applied bool
+}
+
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []migration
+}
+
+func New(pool *pgxpool.Pool, fsys fs.FS) (*Migrator, error) {
+	migrations, err := load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		pool:       pool,
+		migrations: migrations,
+	}, nil
+}
+
+func load(fsys fs.FS) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint64]*migration)
+	for _, entry := range entries {
+		matches := fileNameRe.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, name: matches[2]}
+			byVersion[version] = m
+		}
+
+		if matches[3] == "up" {
+			m.up = string(body)
+		} else {
+			m.down = string(body)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.version, m.name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
+
+// Up applies up to steps pending migrations, all of them if steps <= 0.
+func (m *Migrator) Up(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		current, err := m.currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		applied := 0
+		for _, mg := range m.migrations {
+			if mg.version <= current {
+				continue
+			}
+			if steps > 0 && applied == steps {
+				break
+			}
+
+			log.Printf("applying migration %d_%s", mg.version, mg.name)
+			err := m.apply(ctx, conn, mg.up, mg.version)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", mg.version, mg.name, err)
+			}
+			applied++
+		}
+
+		if applied == 0 {
+			log.Println("no pending migrations")
+		}
+
+		return nil
+	})
+}
+
+// Down rolls back steps applied migrations, all of them if steps <= 0.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		current, err := m.currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		reverted := 0
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mg := m.migrations[i]
+			if mg.version > current {
+				continue
+			}
+			if steps > 0 && reverted == steps {
+				break
+			}
+			if mg.down == "" {
+				return fmt.Errorf("migration %d_%s has no down file", mg.version, mg.name)
+			}
+
+			var previous uint64
+			if i > 0 {
+				previous = m.migrations[i-1].version
+			}
+
+			log.Printf("reverting migration %d_%s", mg.version, mg.name)
+			err := m.apply(ctx, conn, mg.down, previous)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", mg.version, mg.name, err)
+			}
+			reverted++
+		}
+
+		return nil
+	})
+}
+
+// Version returns the current schema version, 0 if nothing has been applied.
+func (m *Migrator) Version(ctx context.Context) (uint64, bool, error) {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer conn.Release()
+
+	return m.readVersion(ctx, conn)
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	current, _, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		statuses = append(statuses, Status{
+			Version: mg.version,
+			Name:    mg.name,
+			Applied: mg.version <= current,
+		})
+	}
+
+	return statuses, nil
+}
+
+// apply runs the migration script and records the new version in one
+// transaction, so a failed migration never leaves the schema half-applied.
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, script string, version uint64) error {
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, script)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, "DELETE FROM schema_migrations")
+		if err != nil {
+			return err
+		}
+
+		if version == 0 {
+			return nil
+		}
+
+		_, err = tx.Exec(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)", int64(version))
+		return err
+	})
+}
+
+func (m *Migrator) currentVersion(ctx context.Context, conn *pgxpool.Conn) (uint64, error) {
+	version, dirty, err := m.readVersion(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+
+	if dirty {
+		return 0, fmt.Errorf("version %d: %w", version, ErrDirty)
+	}
+
+	return version, nil
+}
+
+func (m *Migrator) readVersion(ctx context.Context, conn *pgxpool.Conn) (uint64, bool, error) {
+	_, err := conn.Exec(ctx, createVersionTable)
+	if err != nil {
+		return 0, false, err
+	}
+
+	var (
+		version int64
+		dirty   bool
+	)
+	err = conn.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return uint64(version), dirty, nil
+}
+
+// withLock runs f on a dedicated connection holding a session-level advisory
+// lock. Other replicas block on the lock and then see the migrations applied.
+func (m *Migrator) withLock(ctx context.Context, f func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, "SELECT pg_advisory_lock($1)", advisoryLockKey)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockKey)
+		if err != nil {
+			log.Println("failed to release migration lock:", err)
+		}
+	}()
+
+	return f(conn)
+}
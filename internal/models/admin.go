@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// AdminUser represents a user as seen by administrators
+// @Description  User account details for administrators
+type AdminUser struct {
+	ID            string     `json:"id"`
+	Email         string     `json:"email"`
+	EmailVerified bool       `json:"email_verified"`
+	UserName      string     `json:"username"`
+	Role          string     `json:"role"`
+	TOTPEnabled   bool       `json:"totp_enabled"`
+	DisabledAt    *time.Time `json:"disabled_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// UserList represents a page of users
+// @Description  Page of users and the total number of matches
+type UserList struct {
+	Users []AdminUser `json:"users"`
+	Total int64       `json:"total"`
+}
+
+// UserFilter represents user search parameters
+type UserFilter struct {
+	Query  string
+	Limit  int
+	Offset int
+}
+
+// SetRoleInput represents role change request
+// @Description  New role of the user
+type SetRoleInput struct {
+	Role string `json:"role" validate:"required,oneof=user admin"`
+}
+
+// SystemStats represents service-wide counters
+// @Description  Service-wide counters
+type SystemStats struct {
+	Users          int64 `json:"users"`
+	VerifiedUsers  int64 `json:"verified_users"`
+	DisabledUsers  int64 `json:"disabled_users"`
+	Admins         int64 `json:"admins"`
+	TOTPUsers      int64 `json:"totp_users"`
+	Transactions   int64 `json:"transactions"`
+	ActiveSessions int64 `json:"active_sessions"`
+	APITokens      int64 `json:"api_tokens"`
+}
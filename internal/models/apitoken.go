@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// CreateAPITokenInput represents a new personal access token
+// @Description  Personal access token parameters. Scopes are read, write or <resource>:<read|write> for transactions and profile
+type CreateAPITokenInput struct {
+	Name      string     `json:"name" validate:"required,max=64"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,max=6,dive,oneof=read write transactions:read transactions:write profile:read profile:write"`
+	ExpiresAt *time.Time `json:"expires_at" validate:"omitempty,gt"`
+}
+
+// APIToken represents a personal access token without its secret
+// @Description  Personal access token
+type APIToken struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatedAPIToken represents a newly created personal access token
+// @Description  Personal access token with its secret, shown only once
+type CreatedAPIToken struct {
+	APIToken
+	Token string `json:"token"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Действия в журнале аудита
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+	// AuditRestore — возврат из корзины.
+	AuditRestore = "restore"
+)
+
+// AuditEntry represents a single change in the audit log
+// @Description  Audit log entry with snapshots of the entity before and after the change
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	ActorID   *string         `json:"actor_id"`
+	UserID    *string         `json:"user_id"`
+	LedgerID  *string         `json:"ledger_id"`
+	Entity    string          `json:"entity"`
+	EntityID  string          `json:"entity_id"`
+	Action    string          `json:"action"`
+	Before    json.RawMessage `json:"before" swaggertype:"object"`
+	After     json.RawMessage `json:"after" swaggertype:"object"`
+	RequestID string          `json:"request_id"`
+	IP        string          `json:"ip"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AuditFilter represents audit log query parameters
+type AuditFilter struct {
+	Entity   string
+	EntityID string
+	// BeforeID возвращает записи старше указанной, для постраничного чтения.
+	BeforeID int64
+	Limit    int
+}
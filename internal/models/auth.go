@@ -2,6 +2,12 @@ package models
 
 import "time"
 
+// Роли пользователей
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 // Tokens represents authentication tokens
 // @Description  Authentication tokens response
 type Tokens struct {
@@ -12,16 +18,25 @@ type Tokens struct {
 // SignInInput represents user login credentials
 // @Description  User login credentials
 type SignInInput struct {
-	Username string `json:"username" validate:"required"`
-	Password string `json:"password" validate:"required"`
+	Login    string `json:"login" validate:"required_without=Username,max=254"`
+	Username string `json:"username" validate:"required_without=Login,max=254"` // устаревшее поле, вместо него login
+	Password string `json:"password" validate:"required,max=72"`
+}
+
+// Identifier returns the username or email the user signs in with.
+func (i SignInInput) Identifier() string {
+	if i.Login != "" {
+		return i.Login
+	}
+	return i.Username
 }
 
 // SignUpInput represents user registration data
 // @Description  User registration data
 type SignUpInput struct {
-	Email    string `json:"email" validate:"required"`
-	UserName string `json:"username" validate:"required"`
-	Password string `json:"password" validate:"required"`
+	Email    string `json:"email" validate:"required,email,max=254"`
+	UserName string `json:"username" validate:"required,min=3,max=32,excludes=@"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
 }
 
 // UserInfo represents user information
@@ -31,16 +46,20 @@ type UserInfo struct {
 	Email     string    `json:"email"`
 	UserName  string    `json:"username"`
 	Password  string    `json:"password"`
+	Role      string    `json:"role"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
-// UserInfo represents user information
-// @Description  User information
+// UserInfoWithoutPass represents user profile
+// @Description  User profile
 type UserInfoWithoutPass struct {
-	ID        string    `json:"id"`
-	Email     string    `json:"email"`
-	UserName  string    `json:"username"`
-	CreatedAt time.Time `json:"created_at"`
+	ID            string    `json:"id"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	UserName      string    `json:"username"`
+	Role          string    `json:"role"`
+	CreatedAt     time.Time `json:"created_at"`
+	Version       int64     `json:"version"`
 }
 
 // RefreshInput represents refresh token request
@@ -48,3 +67,77 @@ type UserInfoWithoutPass struct {
 type RefreshInput struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 }
+
+// UpdateProfileInput represents profile changes
+// @Description  Profile changes, omitted fields are left as is
+type UpdateProfileInput struct {
+	Email    *string `json:"email" validate:"omitempty,email,max=254"`
+	UserName *string `json:"username" validate:"omitempty,min=3,max=32,excludes=@"`
+}
+
+// ChangePasswordInput represents password change request
+// @Description  Password change request
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" validate:"required,max=72"`
+	NewPassword     string `json:"new_password" validate:"required,min=8,max=72,nefield=CurrentPassword"`
+}
+
+// DeleteAccountInput represents account deletion request
+// @Description  Account deletion request
+type DeleteAccountInput struct {
+	Password string `json:"password" validate:"required,max=72"`
+}
+
+// ForgotPasswordInput represents password reset request
+// @Description  Password reset request
+type ForgotPasswordInput struct {
+	Email string `json:"email" validate:"required,email,max=254"`
+}
+
+// ResetPasswordInput represents new password set with a reset token
+// @Description  New password with the reset token from email
+type ResetPasswordInput struct {
+	Token       string `json:"token" validate:"required,max=128"`
+	NewPassword string `json:"new_password" validate:"required,min=8,max=72"`
+}
+
+// MFAChallenge is returned by sign-in instead of tokens when 2FA is enabled
+// @Description  Second sign-in step is required
+type MFAChallenge struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+// SignInMFAInput represents the second sign-in step
+// @Description  MFA challenge token with a TOTP or recovery code
+type SignInMFAInput struct {
+	MFAToken     string `json:"mfa_token" validate:"required"`
+	Code         string `json:"code" validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code" validate:"omitempty,max=32"`
+}
+
+// MFAEnrollment represents a new TOTP secret
+// @Description  TOTP secret and otpauth URI for authenticator apps
+type MFAEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// ConfirmMFAInput represents TOTP enrolment confirmation
+// @Description  Code from the authenticator app
+type ConfirmMFAInput struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+// RecoveryCodes represents one-time recovery codes
+// @Description  One-time recovery codes, shown only once
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// DisableMFAInput represents 2FA disabling request
+// @Description  Current password and a TOTP code
+type DisableMFAInput struct {
+	Password string `json:"password" validate:"required,max=72"`
+	Code     string `json:"code" validate:"required,len=6,numeric"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Результат обработки одной операции в пакетном запросе
+const (
+	BatchItemOK     = "ok"
+	BatchItemFailed = "failed"
+)
+
+// BatchCreateInput represents transactions created in one request
+// @Description  Up to 5000 transactions, created all together or not at all. Without category_id the category comes from rules or, when confident, from your history
+type BatchCreateInput struct {
+	Transactions []Transaction `validate:"required,min=1,max=5000,dive" json:"transactions"`
+}
+
+// BatchCreateResult represents created transactions
+// @Description  IDs of created transactions in the order of the request
+type BatchCreateResult struct {
+	IDs []string `json:"ids"`
+}
+
+// TransactionFilter represents a selection of live transactions of a ledger
+// @Description  Transactions of the ledger matching all given conditions
+type TransactionFilter struct {
+	LedgerID   string     `validate:"required,uuid" json:"ledger_id"`
+	CategoryID string     `validate:"omitempty,uuid" json:"category_id"`
+	DateFrom   *time.Time `json:"date_from"`
+	DateTo     *time.Time `json:"date_to"`
+}
+
+// BatchTarget represents a transaction of a bulk operation
+// @Description  Transaction ID and the version it was read at. A transaction changed since then is reported as version_mismatch and skipped
+type BatchTarget struct {
+	ID      string `validate:"required,uuid" json:"id"`
+	Version int64  `validate:"required,min=1" json:"version"`
+}
+
+// BatchUpdateInput represents changes of many transactions
+// @Description  Transactions are selected by ids with versions or by filter. A filter works with what matches at the moment of the request, last writer wins. tag_ids replaces your tags on them, an empty list removes them
+type BatchUpdateInput struct {
+	IDs        []BatchTarget      `validate:"required_without=Filter,excluded_with=Filter,max=5000,dive" json:"ids"`
+	Filter     *TransactionFilter `validate:"required_without=IDs" json:"filter"`
+	CategoryID *string            `validate:"omitempty,uuid" json:"category_id"`
+	TagIDs     []string           `validate:"omitempty,max=50,dive,uuid" json:"tag_ids"`
+}
+
+// BatchDeleteInput represents transactions moved to the trash at once
+// @Description  Transactions are selected by ids with versions or by filter. A filter works with what matches at the moment of the request, last writer wins
+type BatchDeleteInput struct {
+	IDs    []BatchTarget      `validate:"required_without=Filter,excluded_with=Filter,max=5000,dive" json:"ids"`
+	Filter *TransactionFilter `validate:"required_without=IDs" json:"filter"`
+}
+
+// BatchItemResult represents the outcome for one transaction
+type BatchItemResult struct {
+	ID      string `json:"id"`
+	Status  string `json:"status"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// BatchResult represents the outcome of a bulk operation
+// @Description  Per-transaction report. Failed items are skipped, the rest are changed
+type BatchResult struct {
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Items     []BatchItemResult `json:"items"`
+}
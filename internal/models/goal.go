@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// Источники взносов в цель
+const (
+	ContributionIncome   = "income"
+	ContributionTransfer = "transfer"
+)
+
+// Goal represents a savings goal of a ledger
+// @Description  Savings goal. Incomes recorded for the goal and, if category_id is set, transactions of that category since start_date count as contributions
+type Goal struct {
+	ID           string       `json:"id"`
+	LedgerID     string       `json:"ledger_id"`
+	UserID       *string      `json:"user_id"`
+	Name         string       `json:"name"`
+	TargetAmount float64      `json:"target_amount"`
+	TargetDate   *time.Time   `json:"target_date"`
+	StartDate    time.Time    `json:"start_date"`
+	CategoryID   *string      `json:"category_id"`
+	CreatedAt    time.Time    `json:"created_at"`
+	Version      int64        `json:"version"`
+	Progress     GoalProgress `json:"progress"`
+}
+
+// GoalProgress represents progress of a savings goal computed by the server
+// @Description  required_monthly is set when the goal has a target date, projected_date when there were contributions in the last 90 days
+type GoalProgress struct {
+	Saved           float64    `json:"saved"`
+	Remaining       float64    `json:"remaining"`
+	Percent         float64    `json:"percent"`
+	Completed       bool       `json:"completed"`
+	MonthlyRate     float64    `json:"monthly_rate"`
+	RequiredMonthly *float64   `json:"required_monthly"`
+	ProjectedDate   *time.Time `json:"projected_date"`
+}
+
+// GoalInput represents savings goal creation or replacement request
+// @Description  Goal definition. Without start_date a new goal starts today and a replaced one keeps its start date. target_date has to be after the start date
+type GoalInput struct {
+	Name         string     `json:"name" validate:"required,max=100"`
+	TargetAmount float64    `json:"target_amount" validate:"required,gt=0"`
+	TargetDate   *time.Time `json:"target_date"`
+	StartDate    *time.Time `json:"start_date"`
+	CategoryID   *string    `json:"category_id" validate:"omitempty,uuid"`
+}
+
+// ContributionInput represents a contribution to a savings goal
+// @Description  Contribution recorded as your income linked to the goal
+type ContributionInput struct {
+	Amount  float64   `json:"amount" validate:"required,gt=0"`
+	Comment string    `json:"comment" validate:"max=1000"`
+	Date    time.Time `json:"date" validate:"required,notfarfuture"`
+}
+
+// Contribution represents money put towards a savings goal
+// @Description  Income recorded for the goal or a transaction of its category
+type Contribution struct {
+	ID        string    `json:"id"`
+	Source    string    `json:"source"`
+	UserID    string    `json:"user_id"`
+	Amount    float64   `json:"amount"`
+	Comment   string    `json:"comment"`
+	Date      time.Time `json:"date"`
+	CreatedAt time.Time `json:"created_at"`
+	GoalID    string    `json:"goal_id"`
+	// DeletedAt заполнен только у взносов в корзине.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
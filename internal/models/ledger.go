@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// Роли участников книги
+const (
+	LedgerOwner  = "owner"
+	LedgerEditor = "editor"
+	LedgerViewer = "viewer"
+)
+
+// Ledger represents a ledger the user is a member of
+// @Description  Ledger with the role of the current user. The personal ledger has the same ID as the user
+type Ledger struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	Version   int64     `json:"version"`
+}
+
+// LedgerInput represents ledger creation or rename request
+// @Description  Ledger name
+type LedgerInput struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// LedgerMember represents a member of a ledger
+// @Description  Ledger member
+type LedgerMember struct {
+	UserID    string    `json:"user_id"`
+	UserName  string    `json:"username"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MemberRoleInput represents member role change request
+// @Description  New role of the member
+type MemberRoleInput struct {
+	Role string `json:"role" validate:"required,oneof=owner editor viewer"`
+}
+
+// InviteInput represents an invitation to a ledger
+// @Description  Username or email of the invited user and their role
+type InviteInput struct {
+	Login string `json:"login" validate:"required,max=254"`
+	Role  string `json:"role" validate:"required,oneof=owner editor viewer"`
+}
+
+// Invitation represents a pending invitation of the current user
+// @Description  Pending invitation to a ledger
+type Invitation struct {
+	ID         string    `json:"id"`
+	LedgerID   string    `json:"ledger_id"`
+	LedgerName string    `json:"ledger_name"`
+	Role       string    `json:"role"`
+	InvitedBy  string    `json:"invited_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Category represents a transaction category of a ledger
+// @Description  Transaction category
+type Category struct {
+	ID       string `json:"id"`
+	LedgerID string `json:"ledger_id"`
+	Name     string `json:"name" validate:"required,max=100"`
+	Version  int64  `json:"version"`
+	// DeletedAt заполнен только у категорий в корзине.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// Rule represents an auto-categorisation rule of a ledger
+// @Description  Rule with conditions and actions. All given conditions must match. Rules are checked by priority, lowest first, and the first matching rule is applied. weekdays uses 1 for Monday and 7 for Sunday
+type Rule struct {
+	ID       string `json:"id"`
+	LedgerID string `json:"ledger_id"`
+	// UserID — кто последним менял правило, правило ставит его теги.
+	UserID   *string `json:"user_id"`
+	Name     string  `json:"name"`
+	Priority int     `json:"priority"`
+	Enabled  bool    `json:"enabled"`
+
+	CommentContains *string  `json:"comment_contains"`
+	CommentRegex    *string  `json:"comment_regex"`
+	AmountMin       *float64 `json:"amount_min"`
+	AmountMax       *float64 `json:"amount_max"`
+	AuthorID        *string  `json:"author_id"`
+	Weekdays        []int    `json:"weekdays"`
+
+	CategoryID *string  `json:"category_id"`
+	TagIDs     []string `json:"tag_ids"`
+
+	CreatedAt time.Time `json:"created_at"`
+	Version   int64     `json:"version"`
+}
+
+// RuleInput represents rule creation or replacement request
+// @Description  Rule definition. At least one condition and one action are required, tag_ids must be your tags
+type RuleInput struct {
+	Name     string `json:"name" validate:"required,max=100"`
+	Priority int    `json:"priority" validate:"min=0,max=10000"`
+	// Enabled по умолчанию true.
+	Enabled *bool `json:"enabled"`
+
+	CommentContains *string  `json:"comment_contains" validate:"omitempty,min=1,max=200"`
+	CommentRegex    *string  `json:"comment_regex" validate:"omitempty,min=1,max=500"`
+	AmountMin       *float64 `json:"amount_min" validate:"omitempty,gte=0"`
+	AmountMax       *float64 `json:"amount_max" validate:"omitempty,gte=0"`
+	AuthorID        *string  `json:"author_id" validate:"omitempty,uuid"`
+	Weekdays        []int    `json:"weekdays" validate:"omitempty,max=7,unique,dive,min=1,max=7"`
+
+	CategoryID *string  `json:"category_id" validate:"omitempty,uuid"`
+	TagIDs     []string `json:"tag_ids" validate:"omitempty,max=50,unique,dive,uuid"`
+}
+
+// RuleTestResult represents a dry run of a rule against existing transactions
+// @Description  Number of live transactions of the ledger the rule matches and the most recent of them
+type RuleTestResult struct {
+	Matched      int           `json:"matched"`
+	Transactions []Transaction `json:"transactions"`
+}
+
+// ApplyRulesInput represents re-applying rules to existing transactions
+// @Description  Optional date range of transactions to re-check, all live transactions by default
+type ApplyRulesInput struct {
+	DateFrom *time.Time `json:"date_from"`
+	DateTo   *time.Time `json:"date_to"`
+}
+
+// RuleApplyItem represents a transaction changed by a rule
+type RuleApplyItem struct {
+	TransactionID string `json:"transaction_id"`
+	RuleID        string `json:"rule_id"`
+}
+
+// RuleApplyResult represents the outcome of re-applying rules
+// @Description  Number of checked transactions and the ones a rule changed
+type RuleApplyResult struct {
+	Checked int             `json:"checked"`
+	Changed int             `json:"changed"`
+	Items   []RuleApplyItem `json:"items"`
+}
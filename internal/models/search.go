@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// SearchFilter represents search query parameters
+type SearchFilter struct {
+	Query string
+	// LedgerID пустой — поиск по всем книгам пользователя.
+	LedgerID string
+	DateFrom *time.Time
+	DateTo   *time.Time
+	Limit    int
+	Offset   int
+}
+
+// SearchResult represents a transaction found by search
+// @Description  Found transaction, most relevant first. snippet is HTML-escaped comment text with matches wrapped in <mark>
+type SearchResult struct {
+	Transaction  Transaction `json:"transaction"`
+	CategoryName string      `json:"category_name"`
+	Tags         []string    `json:"tags"`
+	Rank         float64     `json:"rank"`
+	Snippet      string      `json:"snippet"`
+}
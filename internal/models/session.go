@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Session represents a signed-in device
+// @Description  Signed-in device
+type Session struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
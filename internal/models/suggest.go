@@ -0,0 +1,9 @@
+package models
+
+// CategorySuggestion represents a category suggested from history
+// @Description  Category the user would likely choose, with the model's probability from 0 to 1
+type CategorySuggestion struct {
+	CategoryID   string  `json:"category_id"`
+	CategoryName string  `json:"category_name"`
+	Probability  float64 `json:"probability"`
+}
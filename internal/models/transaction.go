@@ -3,13 +3,26 @@ package models
 import "time"
 
 // Transaction represents a financial transaction
-// @Description  Financial transaction data
+// @Description  Financial transaction data. ledger_id defaults to the personal ledger, user_id is the author. category_id may be omitted when a rule of the ledger assigns it
 type Transaction struct {
 	ID         string    `json:"id"`
-	UserID     string    `validate:"required" json:"user_id"`
-	Amount     float64   `validate:"required" json:"amount"`
-	CategoryID string    `validate:"required" json:"category_id"`
-	Comment    string    `validate:"required" json:"comment"`
-	Date       time.Time `validate:"required" json:"date"`
+	LedgerID   string    `validate:"omitempty,uuid" json:"ledger_id"`
+	UserID     string    `json:"user_id"`
+	Amount     float64   `validate:"required,gt=0" json:"amount"`
+	CategoryID string    `validate:"omitempty,uuid" json:"category_id"`
+	Comment    string    `validate:"required,max=1000" json:"comment"`
+	Date       time.Time `validate:"required,notfarfuture" json:"date"`
 	CreatedAt  time.Time `json:"created_at"`
+	Version    int64     `json:"version"`
+	// DeletedAt заполнен только у операций в корзине.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// UpdateTransactionInput represents transaction changes
+// @Description  Transaction changes, omitted fields are left as is
+type UpdateTransactionInput struct {
+	Amount     *float64   `validate:"omitempty,gt=0" json:"amount"`
+	CategoryID *string    `validate:"omitempty,uuid" json:"category_id"`
+	Comment    *string    `validate:"omitempty,min=1,max=1000" json:"comment"`
+	Date       *time.Time `validate:"omitempty,notfarfuture" json:"date"`
 }
@@ -0,0 +1,9 @@
+package models
+
+// Trash represents deleted data of a ledger that can still be restored
+// @Description  Deleted transactions, categories and goal contributions of the ledger, most recently deleted first
+type Trash struct {
+	Transactions  []Transaction  `json:"transactions"`
+	Categories    []Category     `json:"categories"`
+	Contributions []Contribution `json:"contributions"`
+}
@@ -0,0 +1,150 @@
+// Package rules matches transactions against auto-categorisation rules of a ledger.
+package rules
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"simple-finance/internal/errs"
+	"simple-finance/internal/models"
+)
+
+// Engine picks the rule to apply to a transaction.
+type Engine struct {
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	rule     models.Rule
+	contains string
+	regex    *regexp.Regexp
+}
+
+// New prepares rules for matching. Rules have to be sorted by priority, the
+// caller decides whether disabled rules take part.
+func New(rules []models.Rule) (*Engine, error) {
+	compiled := make([]compiledRule, 0, len(rules))
+
+	for _, rule := range rules {
+		c := compiledRule{rule: rule}
+
+		if rule.CommentContains != nil {
+			c.contains = strings.ToLower(*rule.CommentContains)
+		}
+
+		if rule.CommentRegex != nil {
+			regex, err := regexp.Compile(*rule.CommentRegex)
+			if err != nil {
+				return nil, errs.Validation("rule_invalid_regex", "comment_regex is not a valid regular expression")
+			}
+			c.regex = regex
+		}
+
+		compiled = append(compiled, c)
+	}
+
+	return &Engine{rules: compiled}, nil
+}
+
+// Match returns the first rule whose conditions all hold for the transaction.
+func (e *Engine) Match(transaction models.Transaction) (models.Rule, bool) {
+	for _, c := range e.rules {
+		if c.matches(transaction) {
+			return c.rule, true
+		}
+	}
+
+	return models.Rule{}, false
+}
+
+func (c compiledRule) matches(transaction models.Transaction) bool {
+	rule := c.rule
+
+	if rule.CommentContains != nil && !strings.Contains(strings.ToLower(transaction.Comment), c.contains) {
+		return false
+	}
+	if c.regex != nil && !c.regex.MatchString(transaction.Comment) {
+		return false
+	}
+	if rule.AmountMin != nil && transaction.Amount < *rule.AmountMin {
+		return false
+	}
+	if rule.AmountMax != nil && transaction.Amount > *rule.AmountMax {
+		return false
+	}
+	if rule.AuthorID != nil && !strings.EqualFold(*rule.AuthorID, transaction.UserID) {
+		return false
+	}
+	if len(rule.Weekdays) > 0 && !slices.Contains(rule.Weekdays, isoWeekday(transaction.Date)) {
+		return false
+	}
+
+	return true
+}
+
+// isoWeekday нумерует дни с понедельника, как принято у пользователей.
+func isoWeekday(date time.Time) int {
+	if date.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(date.Weekday())
+}
+
+// FromInput builds a rule from the request. Enabled defaults to true.
+func FromInput(input models.RuleInput) models.Rule {
+	rule := models.Rule{
+		Name:            input.Name,
+		Priority:        input.Priority,
+		Enabled:         true,
+		CommentContains: input.CommentContains,
+		CommentRegex:    input.CommentRegex,
+		AmountMin:       input.AmountMin,
+		AmountMax:       input.AmountMax,
+		AuthorID:        input.AuthorID,
+		Weekdays:        input.Weekdays,
+		CategoryID:      input.CategoryID,
+		TagIDs:          input.TagIDs,
+	}
+
+	if input.Enabled != nil {
+		rule.Enabled = *input.Enabled
+	}
+	if rule.Weekdays == nil {
+		rule.Weekdays = []int{}
+	}
+	if rule.TagIDs == nil {
+		rule.TagIDs = []string{}
+	}
+
+	return rule
+}
+
+// Validate checks what struct tags cannot express: a rule has to match
+// something and do something, and its regular expression has to compile.
+func Validate(input models.RuleInput) error {
+	hasCondition := input.CommentContains != nil || input.CommentRegex != nil ||
+		input.AmountMin != nil || input.AmountMax != nil ||
+		input.AuthorID != nil || len(input.Weekdays) > 0
+	if !hasCondition {
+		return errs.Validation("rule_without_conditions", "rule needs at least one condition")
+	}
+
+	if input.CategoryID == nil && len(input.TagIDs) == 0 {
+		return errs.Validation("rule_without_actions", "rule needs a category or tags to assign")
+	}
+
+	if input.AmountMin != nil && input.AmountMax != nil && *input.AmountMin > *input.AmountMax {
+		return errs.Validation("rule_invalid_amount_range", "amount_min must not be greater than amount_max")
+	}
+
+	if input.CommentRegex != nil {
+		_, err := regexp.Compile(*input.CommentRegex)
+		if err != nil {
+			return errs.Validation("rule_invalid_regex", "comment_regex is not a valid regular expression")
+		}
+	}
+
+	return nil
+}
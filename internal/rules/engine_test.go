@@ -0,0 +1,203 @@
+package rules
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"simple-finance/internal/errs"
+	"simple-finance/internal/models"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func newEngine(t *testing.T, rules ...models.Rule) *Engine {
+	t.Helper()
+
+	engine, err := New(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return engine
+}
+
+func TestMatchFirstWins(t *testing.T) {
+	// Правила приходят отсортированными по приоритету, выигрывает первое подходящее.
+	engine := newEngine(t,
+		models.Rule{ID: "coffee", Priority: 1, CommentContains: ptr("coffee")},
+		models.Rule{ID: "food", Priority: 2, CommentContains: ptr("o")},
+		models.Rule{ID: "big", Priority: 3, AmountMin: ptr(1000.0)},
+	)
+
+	tests := []struct {
+		comment string
+		amount  float64
+		want    string
+	}{
+		{"coffee to go", 5000, "coffee"},
+		{"groceries", 5000, "food"},
+		{"rent", 5000, "big"},
+		{"rent", 10, ""},
+	}
+
+	for _, tt := range tests {
+		rule, ok := engine.Match(models.Transaction{Comment: tt.comment, Amount: tt.amount})
+		if ok != (tt.want != "") || rule.ID != tt.want {
+			t.Errorf("Match(%q, %v) = %q, %v, want %q", tt.comment, tt.amount, rule.ID, ok, tt.want)
+		}
+	}
+}
+
+func TestMatchAllConditions(t *testing.T) {
+	engine := newEngine(t, models.Rule{
+		ID:              "taxi",
+		CommentContains: ptr("taxi"),
+		AmountMax:       ptr(100.0),
+	})
+
+	if _, ok := engine.Match(models.Transaction{Comment: "taxi", Amount: 500}); ok {
+		t.Error("rule matched although the amount condition fails")
+	}
+	if _, ok := engine.Match(models.Transaction{Comment: "taxi", Amount: 50}); !ok {
+		t.Error("rule did not match although all conditions hold")
+	}
+}
+
+func TestCommentContainsIgnoresCase(t *testing.T) {
+	engine := newEngine(t, models.Rule{ID: "market", CommentContains: ptr("ПяТёРоЧкА")})
+
+	for _, comment := range []string{"пятёрочка у дома", "ПЯТЁРОЧКА", "Магазин Пятёрочка"} {
+		if _, ok := engine.Match(models.Transaction{Comment: comment}); !ok {
+			t.Errorf("Match(%q) = false", comment)
+		}
+	}
+	if _, ok := engine.Match(models.Transaction{Comment: "магнит"}); ok {
+		t.Error("Match(\"магнит\") = true")
+	}
+}
+
+func TestCommentRegex(t *testing.T) {
+	engine := newEngine(t, models.Rule{ID: "order", CommentRegex: ptr(`^order #\d+$`)})
+
+	if _, ok := engine.Match(models.Transaction{Comment: "order #123"}); !ok {
+		t.Error("regex did not match")
+	}
+	if _, ok := engine.Match(models.Transaction{Comment: "order #abc"}); ok {
+		t.Error("regex matched")
+	}
+}
+
+func TestAmountBoundsInclusive(t *testing.T) {
+	engine := newEngine(t, models.Rule{ID: "range", AmountMin: ptr(100.0), AmountMax: ptr(200.0)})
+
+	tests := []struct {
+		amount float64
+		want   bool
+	}{
+		{99.99, false},
+		{100, true},
+		{150, true},
+		{200, true},
+		{200.01, false},
+	}
+
+	for _, tt := range tests {
+		if _, ok := engine.Match(models.Transaction{Amount: tt.amount}); ok != tt.want {
+			t.Errorf("Match(amount %v) = %v, want %v", tt.amount, ok, tt.want)
+		}
+	}
+}
+
+func TestAuthor(t *testing.T) {
+	engine := newEngine(t, models.Rule{ID: "author", AuthorID: ptr("0F8FAD5B-D9CB-469F-A165-70867728950E")})
+
+	if _, ok := engine.Match(models.Transaction{UserID: "0f8fad5b-d9cb-469f-a165-70867728950e"}); !ok {
+		t.Error("author did not match regardless of case")
+	}
+	if _, ok := engine.Match(models.Transaction{UserID: "7c9e6679-7425-40de-944b-e07fc1f90ae7"}); ok {
+		t.Error("another author matched")
+	}
+}
+
+func TestISOWeekdays(t *testing.T) {
+	// 2024-01-01 — понедельник.
+	monday := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		days int
+		want int
+	}{
+		{0, 1},
+		{5, 6},
+		{6, 7},
+	}
+
+	for _, tt := range tests {
+		date := monday.AddDate(0, 0, tt.days)
+		if got := isoWeekday(date); got != tt.want {
+			t.Errorf("isoWeekday(%s) = %d, want %d", date.Weekday(), got, tt.want)
+		}
+	}
+
+	engine := newEngine(t, models.Rule{ID: "weekend", Weekdays: []int{6, 7}})
+
+	if _, ok := engine.Match(models.Transaction{Date: monday.AddDate(0, 0, 6)}); !ok {
+		t.Error("Sunday did not match weekday 7")
+	}
+	if _, ok := engine.Match(models.Transaction{Date: monday}); ok {
+		t.Error("Monday matched the weekend rule")
+	}
+}
+
+func TestInvalidRegex(t *testing.T) {
+	_, err := New([]models.Rule{{ID: "broken", CommentRegex: ptr("(")}})
+	assertCode(t, err, "rule_invalid_regex")
+
+	err = Validate(models.RuleInput{CommentRegex: ptr("("), CategoryID: ptr("c")})
+	assertCode(t, err, "rule_invalid_regex")
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		input models.RuleInput
+		code  string
+	}{
+		{"no conditions", models.RuleInput{CategoryID: ptr("c")}, "rule_without_conditions"},
+		{"no actions", models.RuleInput{CommentContains: ptr("x")}, "rule_without_actions"},
+		{
+			"inverted range",
+			models.RuleInput{AmountMin: ptr(10.0), AmountMax: ptr(5.0), CategoryID: ptr("c")},
+			"rule_invalid_amount_range",
+		},
+		{"valid", models.RuleInput{Weekdays: []int{7}, TagIDs: []string{"t"}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.input)
+			if tt.code == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			assertCode(t, err, tt.code)
+		})
+	}
+}
+
+func assertCode(t *testing.T, err error, code string) {
+	t.Helper()
+
+	var domainErr *errs.Error
+	if !errors.As(err, &domainErr) {
+		t.Fatalf("error = %v, want domain error %s", err, code)
+	}
+	if domainErr.Code != code {
+		t.Errorf("code = %s, want %s", domainErr.Code, code)
+	}
+}
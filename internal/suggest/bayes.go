@@ -0,0 +1,165 @@
+// Package suggest predicts the category of a transaction from the user's
+// history with a multinomial naive Bayes classifier over comment tokens and
+// the amount band. Everything runs in process, a model is trained in
+// milliseconds from a few thousand transactions.
+package suggest
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Обрезка слов — грубая замена стемминга: «аптека», «аптеке» и «аптеку»
+// дают один признак.
+const maxTokenRunes = 5
+
+// Example is a categorised transaction the model learns from.
+type Example struct {
+	Comment    string
+	Amount     float64
+	CategoryID string
+}
+
+// Suggestion is a category with the probability the model gives it.
+type Suggestion struct {
+	CategoryID  string
+	Probability float64
+}
+
+// Model holds feature counts per category.
+type Model struct {
+	examples   int
+	docs       map[string]int
+	features   map[string]map[string]int
+	totals     map[string]int
+	vocabulary map[string]struct{}
+}
+
+// Train builds a model from examples. An empty history gives a model that
+// suggests nothing.
+func Train(examples []Example) *Model {
+	m := &Model{
+		docs:       make(map[string]int),
+		features:   make(map[string]map[string]int),
+		totals:     make(map[string]int),
+		vocabulary: make(map[string]struct{}),
+	}
+
+	for _, example := range examples {
+		m.examples++
+		m.docs[example.CategoryID]++
+
+		counts, ok := m.features[example.CategoryID]
+		if !ok {
+			counts = make(map[string]int)
+			m.features[example.CategoryID] = counts
+		}
+
+		for _, feature := range Features(example.Comment, example.Amount) {
+			counts[feature]++
+			m.totals[example.CategoryID]++
+			m.vocabulary[feature] = struct{}{}
+		}
+	}
+
+	return m
+}
+
+// Examples returns the number of transactions the model learned from.
+func (m *Model) Examples() int {
+	return m.examples
+}
+
+// Predict returns up to limit categories, the most probable first.
+// Probabilities of all known categories sum up to one.
+func (m *Model) Predict(comment string, amount float64, limit int) []Suggestion {
+	if m.examples == 0 || limit < 1 {
+		return []Suggestion{}
+	}
+
+	features := Features(comment, amount)
+	vocabulary := float64(len(m.vocabulary))
+
+	scores := make([]Suggestion, 0, len(m.docs))
+	maxScore := math.Inf(-1)
+
+	for categoryID, docs := range m.docs {
+		// Сглаживание Лапласа, чтобы незнакомое слово не обнуляло категорию.
+		score := math.Log(float64(docs) / float64(m.examples))
+		denominator := float64(m.totals[categoryID]) + vocabulary
+
+		for _, feature := range features {
+			score += math.Log((float64(m.features[categoryID][feature]) + 1) / denominator)
+		}
+
+		scores = append(scores, Suggestion{CategoryID: categoryID, Probability: score})
+		maxScore = math.Max(maxScore, score)
+	}
+
+	// Логарифмы переводим в вероятности через log-sum-exp, иначе exp уходит в ноль.
+	var sum float64
+	for i := range scores {
+		scores[i].Probability = math.Exp(scores[i].Probability - maxScore)
+		sum += scores[i].Probability
+	}
+	for i := range scores {
+		scores[i].Probability /= sum
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Probability != scores[j].Probability {
+			return scores[i].Probability > scores[j].Probability
+		}
+		return scores[i].CategoryID < scores[j].CategoryID
+	})
+
+	if len(scores) > limit {
+		scores = scores[:limit]
+	}
+
+	return scores
+}
+
+// Features splits the comment into lowercase word prefixes and adds the
+// amount band, a power of two, so 130 and 250 look alike but 250 and 15000
+// do not. Numbers in the comment are mostly dates and receipts, they are skipped.
+func Features(comment string, amount float64) []string {
+	words := strings.FieldsFunc(strings.ToLower(comment), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	features := make([]string, 0, len(words)+1)
+
+	for _, word := range words {
+		runes := []rune(word)
+		if len(runes) < 2 || isNumber(runes) {
+			continue
+		}
+		if len(runes) > maxTokenRunes {
+			runes = runes[:maxTokenRunes]
+		}
+		features = append(features, "w:"+string(runes))
+	}
+
+	if amount > 0 {
+		band := 0
+		if amount >= 1 {
+			band = int(math.Floor(math.Log2(amount))) + 1
+		}
+		features = append(features, "a:"+strconv.Itoa(band))
+	}
+
+	return features
+}
+
+func isNumber(runes []rune) bool {
+	for _, r := range runes {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
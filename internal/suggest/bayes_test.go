@@ -0,0 +1,158 @@
+package suggest
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func history() []Example {
+	return []Example{
+		{Comment: "Аптека на углу", Amount: 450, CategoryID: "health"},
+		{Comment: "аптеке витамины", Amount: 900, CategoryID: "health"},
+		{Comment: "такси домой", Amount: 350, CategoryID: "transport"},
+		{Comment: "такси в аэропорт", Amount: 1200, CategoryID: "transport"},
+		{Comment: "кофе", Amount: 250, CategoryID: "food"},
+		{Comment: "продукты пятёрочка", Amount: 1800, CategoryID: "food"},
+	}
+}
+
+func TestEmptyHistory(t *testing.T) {
+	m := Train(nil)
+
+	if m.Examples() != 0 {
+		t.Errorf("Examples() = %d, want 0", m.Examples())
+	}
+
+	got := m.Predict("такси", 300, 3)
+	if got == nil || len(got) != 0 {
+		t.Errorf("Predict = %#v, want empty non-nil slice", got)
+	}
+}
+
+func TestPredictLimit(t *testing.T) {
+	m := Train(history())
+
+	if got := m.Predict("такси", 300, 0); len(got) != 0 {
+		t.Errorf("limit 0 gave %d suggestions", len(got))
+	}
+	if got := m.Predict("такси", 300, 2); len(got) != 2 {
+		t.Errorf("limit 2 gave %d suggestions", len(got))
+	}
+}
+
+func TestProbabilitiesSumToOne(t *testing.T) {
+	m := Train(history())
+
+	for _, comment := range []string{"такси", "аптека", "что-то новое", ""} {
+		var sum float64
+		for _, s := range m.Predict(comment, 500, 10) {
+			sum += s.Probability
+		}
+
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("Predict(%q) probabilities sum to %v", comment, sum)
+		}
+	}
+}
+
+func TestPredictOrder(t *testing.T) {
+	m := Train(history())
+
+	got := m.Predict("Такси до работы", 400, 3)
+	if got[0].CategoryID != "transport" {
+		t.Fatalf("top category = %s, want transport", got[0].CategoryID)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i].Probability > got[i-1].Probability {
+			t.Errorf("suggestions are not sorted: %#v", got)
+		}
+	}
+
+	// Слово обрезается до пяти букв, поэтому «аптеку» узнаётся по «аптека».
+	if top := m.Predict("в аптеку", 600, 1); top[0].CategoryID != "health" {
+		t.Errorf("top category = %s, want health", top[0].CategoryID)
+	}
+}
+
+func TestUnseenTokens(t *testing.T) {
+	m := Train(history())
+
+	got := m.Predict("такси абракадабра шмяк", 400, 10)
+	if len(got) != 3 {
+		t.Fatalf("got %d categories, want 3", len(got))
+	}
+	for _, s := range got {
+		if s.Probability <= 0 {
+			t.Errorf("category %s got probability %v", s.CategoryID, s.Probability)
+		}
+	}
+	if got[0].CategoryID != "transport" {
+		t.Errorf("unseen words changed the top category to %s", got[0].CategoryID)
+	}
+}
+
+func TestTieBreak(t *testing.T) {
+	// Категории неразличимы, порядок определяет ID.
+	m := Train([]Example{
+		{Comment: "b", Amount: 0, CategoryID: "b"},
+		{Comment: "c", Amount: 0, CategoryID: "c"},
+		{Comment: "a", Amount: 0, CategoryID: "a"},
+	})
+
+	got := m.Predict("", 0, 3)
+
+	ids := make([]string, 0, len(got))
+	for _, s := range got {
+		ids = append(ids, s.CategoryID)
+	}
+	if !slices.Equal(ids, []string{"a", "b", "c"}) {
+		t.Errorf("order = %v, want [a b c]", ids)
+	}
+	if got[0].Probability != got[2].Probability {
+		t.Errorf("tied categories got %v and %v", got[0].Probability, got[2].Probability)
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	got := Features("Супермаркет, 12.05 чек 123 я", 150)
+	want := []string{"w:супер", "w:чек", "a:8"}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("Features = %v, want %v", got, want)
+	}
+}
+
+func TestAmountBands(t *testing.T) {
+	tests := []struct {
+		amount float64
+		band   string
+	}{
+		{0.5, "a:0"},
+		{0.999, "a:0"},
+		{1, "a:1"},
+		{1.999, "a:1"},
+		{2, "a:2"},
+		{3.99, "a:2"},
+		{4, "a:3"},
+		{120, "a:7"},
+		{150, "a:8"},
+		{127.99, "a:7"},
+		{128, "a:8"},
+		{130, "a:8"},
+		{250, "a:8"},
+		{256, "a:9"},
+		{15000, "a:14"},
+	}
+
+	for _, tt := range tests {
+		got := Features("", tt.amount)
+		if len(got) != 1 || got[0] != tt.band {
+			t.Errorf("Features(%v) = %v, want [%s]", tt.amount, got, tt.band)
+		}
+	}
+
+	if got := Features("", 0); len(got) != 0 {
+		t.Errorf("zero amount gave features %v", got)
+	}
+}
@@ -8,8 +8,22 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Назначения токенов, которые не дают доступа к API, а подтверждают
+// одно конкретное действие.
+const (
+	PurposeEmailVerification = "email_verification"
+	PurposeMFAChallenge      = "mfa_challenge"
+)
+
 type TokenInfo struct {
-	UserID string
+	UserID    string
+	Role      string
+	IssuedAt  time.Time
+	SessionID string
+	// APITokenID и Scopes заполняются только для персональных API-токенов.
+	// У обычной сессии Scopes пустой, и она имеет полный доступ.
+	APITokenID string
+	Scopes     []string
 }
 
 type TokenManager struct {
@@ -25,18 +39,100 @@ func NewTokenManager(signingKey string) (*TokenManager, error) {
 }
 
 func (m *TokenManager) NewJWT(tokenInfo TokenInfo, ttl time.Duration) (string, error) {
-	if tokenInfo.UserID == "" {
+	if tokenInfo.UserID == "" || tokenInfo.Role == "" {
 		return "", fmt.Errorf("userID or user role is empty")
 	}
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"exp":  now.Add(ttl).Unix(),
+		"iat":  now.Unix(),
+		"sub":  tokenInfo.UserID,
+		"role": tokenInfo.Role,
+	}
+	if tokenInfo.SessionID != "" {
+		claims["sid"] = tokenInfo.SessionID
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	return token.SignedString([]byte(m.signingKey))
+}
+
+func (m *TokenManager) Parse(accessToken string) (TokenInfo, error) {
+	claims, err := m.parseClaims(accessToken)
+	if err != nil {
+		return TokenInfo{}, err
+	}
+
+	// Токен подтверждения действия не должен работать как токен доступа.
+	if _, ok := claims["purpose"]; ok {
+		return TokenInfo{}, fmt.Errorf("token is not an access token")
+	}
+
+	userID, ok := claims["sub"].(string)
+	if !ok {
+		return TokenInfo{}, fmt.Errorf("error get user id from token")
+	}
+
+	// У токенов, выпущенных до появления iat, время выпуска остаётся нулевым.
+	var issuedAt time.Time
+	if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+		issuedAt = iat.Time
+	}
+
+	// Токены, выпущенные до появления сессий и ролей, sid и role не содержат.
+	sessionID, _ := claims["sid"].(string)
+	role, _ := claims["role"].(string)
+
+	return TokenInfo{
+		UserID:    userID,
+		Role:      role,
+		IssuedAt:  issuedAt,
+		SessionID: sessionID,
+	}, nil
+}
+
+// NewPurposeJWT creates a token that confirms a single action for the user.
+// data is bound to the token, e.g. the email being verified, so the token
+// stops working once data changes.
+func (m *TokenManager) NewPurposeJWT(purpose, userID, data string, ttl time.Duration) (string, error) {
+	if userID == "" || purpose == "" {
+		return "", fmt.Errorf("userID or purpose is empty")
+	}
+	now := time.Now()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"exp": time.Now().Add(ttl).Unix(),
-		"sub": tokenInfo.UserID,
+		"exp":     now.Add(ttl).Unix(),
+		"iat":     now.Unix(),
+		"sub":     userID,
+		"purpose": purpose,
+		"data":    data,
 	})
 
 	return token.SignedString([]byte(m.signingKey))
 }
 
-func (m *TokenManager) Parse(accessToken string) (TokenInfo, error) {
+// ParsePurpose checks that the token was issued for purpose and returns
+// the user ID and data bound to it.
+func (m *TokenManager) ParsePurpose(token, purpose string) (string, string, error) {
+	claims, err := m.parseClaims(token)
+	if err != nil {
+		return "", "", err
+	}
+
+	if claims["purpose"] != purpose {
+		return "", "", fmt.Errorf("token is not a %s token", purpose)
+	}
+
+	userID, ok := claims["sub"].(string)
+	if !ok {
+		return "", "", fmt.Errorf("error get user id from token")
+	}
+
+	data, _ := claims["data"].(string)
+
+	return userID, data, nil
+}
+
+func (m *TokenManager) parseClaims(token string) (jwt.MapClaims, error) {
 	keyFunc := func(token *jwt.Token) (i interface{}, err error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -44,17 +140,15 @@ func (m *TokenManager) Parse(accessToken string) (TokenInfo, error) {
 
 		return []byte(m.signingKey), nil
 	}
-	token, err := jwt.Parse(accessToken, keyFunc)
+	parsed, err := jwt.Parse(token, keyFunc)
 	if err != nil {
-		return TokenInfo{}, err
+		return nil, err
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
+	claims, ok := parsed.Claims.(jwt.MapClaims)
 	if !ok {
-		return TokenInfo{}, fmt.Errorf("error get user claims from token")
+		return nil, fmt.Errorf("error get user claims from token")
 	}
 
-	return TokenInfo{
-		UserID: claims["sub"].(string),
-	}, nil
+	return claims, nil
 }
@@ -0,0 +1,45 @@
+package tokens
+
+import "strings"
+
+// Ресурсы, доступ к которым ограничивается scope персональных API-токенов.
+// ResourceAccount не выдаётся ни одним scope: управлять аккаунтом, паролем,
+// 2FA и самими токенами можно только из обычной сессии.
+const (
+	ResourceTransactions = "transactions"
+	ResourceProfile      = "profile"
+	ResourceAccount      = "account"
+)
+
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// Allows reports whether the token grants access to the resource. Scopes are
+// either global ("read", "write") or per resource ("transactions:read").
+// Write access implies read access.
+func (t TokenInfo) Allows(resource string, write bool) bool {
+	if t.APITokenID == "" {
+		return true
+	}
+	if resource == ResourceAccount {
+		return false
+	}
+
+	for _, scope := range t.Scopes {
+		scopeResource, access, found := strings.Cut(scope, ":")
+		if !found {
+			scopeResource, access = resource, scope
+		}
+		if scopeResource != resource {
+			continue
+		}
+
+		if access == ScopeWrite || (access == ScopeRead && !write) {
+			return true
+		}
+	}
+
+	return false
+}
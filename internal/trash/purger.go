@@ -0,0 +1,55 @@
+// Package trash removes deleted data once its retention period is over.
+package trash
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"simple-finance/internal/db"
+)
+
+type Purger struct {
+	db        *db.FinanceDB
+	retention time.Duration
+	interval  time.Duration
+	logger    *logrus.Logger
+}
+
+func NewPurger(db *db.FinanceDB, retention, interval time.Duration, logger *logrus.Logger) *Purger {
+	return &Purger{
+		db:        db,
+		retention: retention,
+		interval:  interval,
+		logger:    logger,
+	}
+}
+
+// Run purges the trash right away and then every interval until ctx is done.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Purger) purge(ctx context.Context) {
+	purged, err := p.db.PurgeTrash(ctx, p.retention)
+	if err != nil {
+		// Следующая попытка будет через interval, ошибку достаточно записать.
+		p.logger.Warn(err)
+		return
+	}
+
+	if purged > 0 {
+		p.logger.Infof("purged %d rows from the trash", purged)
+	}
+}
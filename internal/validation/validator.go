@@ -0,0 +1,170 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/ru"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	ruTranslations "github.com/go-playground/validator/v10/translations/ru"
+	"simple-finance/internal/errs"
+)
+
+const (
+	langEn = "en"
+	langRu = "ru"
+
+	// Насколько далеко в будущем может быть дата операции.
+	maxFutureDuration = 31 * 24 * time.Hour
+)
+
+// Переводы для собственных правил и общего сообщения об ошибке.
+var (
+	customTranslations = map[string]map[string]string{
+		langEn: {
+			"notfarfuture": "{0} must not be more than a month in the future",
+		},
+		langRu: {
+			"notfarfuture": "{0} не может быть больше чем на месяц в будущем",
+		},
+	}
+
+	failedMessages = map[string]string{
+		langEn: "request contains invalid fields",
+		langRu: "запрос содержит некорректные поля",
+	}
+)
+
+// Validator checks request structs and reports failures per JSON field
+// in the language the client asked for.
+type Validator struct {
+	validate *validator.Validate
+	uni      *ut.UniversalTranslator
+}
+
+func New() (*Validator, error) {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+
+	// В ответе клиенту нужны имена полей из JSON, а не из Go-структур.
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	err := validate.RegisterValidation("notfarfuture", notFarFuture)
+	if err != nil {
+		return nil, err
+	}
+
+	enLocale := en.New()
+	uni := ut.New(enLocale, enLocale, ru.New())
+
+	enTrans, _ := uni.GetTranslator(langEn)
+	if err := enTranslations.RegisterDefaultTranslations(validate, enTrans); err != nil {
+		return nil, err
+	}
+
+	ruTrans, _ := uni.GetTranslator(langRu)
+	if err := ruTranslations.RegisterDefaultTranslations(validate, ruTrans); err != nil {
+		return nil, err
+	}
+
+	for lang, translations := range customTranslations {
+		trans, _ := uni.GetTranslator(lang)
+		for tag, text := range translations {
+			err := validate.RegisterTranslation(tag, trans, registerTranslation(tag, text), translate)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &Validator{
+		validate: validate,
+		uni:      uni,
+	}, nil
+}
+
+// Struct validates s. Field errors are returned as errs.ErrValidation with
+// messages in the first supported language of acceptLanguage.
+func (v *Validator) Struct(s any, acceptLanguage string) error {
+	err := v.validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return err
+	}
+
+	lang := parseLanguage(acceptLanguage)
+	trans, _ := v.uni.GetTranslator(lang)
+
+	fields := make([]errs.FieldError, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		fields = append(fields, errs.FieldError{
+			Field:   fieldPath(fe),
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: fe.Translate(trans),
+		})
+	}
+
+	return errs.InvalidFields(failedMessages[lang], fields)
+}
+
+// fieldPath returns the JSON path of the field without the root struct name,
+// e.g. "amount" instead of "Transaction.amount".
+func fieldPath(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
+	}
+	return path
+}
+
+// parseLanguage picks the first supported language from an Accept-Language
+// header. Quality values are ignored, clients list languages by preference.
+func parseLanguage(acceptLanguage string) string {
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if _, ok := failedMessages[base]; ok {
+			return base
+		}
+	}
+
+	return langEn
+}
+
+func notFarFuture(fl validator.FieldLevel) bool {
+	date, ok := fl.Field().Interface().(time.Time)
+	if !ok {
+		return false
+	}
+
+	return date.Before(time.Now().Add(maxFutureDuration))
+}
+
+func registerTranslation(tag, text string) validator.RegisterTranslationsFunc {
+	return func(trans ut.Translator) error {
+		return trans.Add(tag, text, false)
+	}
+}
+
+func translate(trans ut.Translator, fe validator.FieldError) string {
+	text, err := trans.T(fe.Tag(), fe.Field())
+	if err != nil {
+		return fe.Error()
+	}
+	return text
+}
@@ -0,0 +1,32 @@
+ALTER TABLE
+    "incomes" DROP CONSTRAINT "incomes_user_id_foreign";
+ALTER TABLE
+    "incomes" ADD CONSTRAINT "incomes_user_id_foreign" FOREIGN KEY("user_id") REFERENCES "users"("id");
+ALTER TABLE
+    "transactions" DROP CONSTRAINT "transactions_user_id_foreign";
+ALTER TABLE
+    "transactions" ADD CONSTRAINT "transactions_user_id_foreign" FOREIGN KEY("user_id") REFERENCES "users"("id");
+
+ALTER TABLE
+    "transaction_tags" DROP CONSTRAINT "transaction_tags_tag_id_foreign";
+ALTER TABLE
+    "transaction_tags" ADD CONSTRAINT "transaction_tags_tag_id_foreign" FOREIGN KEY("tag_id") REFERENCES "tags"("id");
+ALTER TABLE
+    "transaction_tags" DROP CONSTRAINT "transaction_tags_transaction_id_foreign";
+ALTER TABLE
+    "transaction_tags" ADD CONSTRAINT "transaction_tags_transaction_id_foreign" FOREIGN KEY("transaction_id") REFERENCES "transactions"("id");
+
+ALTER TABLE
+    "tags" DROP CONSTRAINT "tags_user_id_foreign";
+ALTER TABLE
+    "categories" DROP CONSTRAINT "categories_user_id_foreign";
+
+ALTER TABLE
+    "transaction_tags" DROP CONSTRAINT "transaction_tags_pkey";
+
+DROP INDEX IF EXISTS "transactions_user_id_date_index";
+
+ALTER TABLE
+    "users" DROP CONSTRAINT "users_email_unique";
+ALTER TABLE
+    "users" DROP CONSTRAINT "users_username_unique";
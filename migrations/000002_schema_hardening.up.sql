@@ -0,0 +1,75 @@
+-- Дубликаты имён пользователей и email переименовываем, а не удаляем,
+-- чтобы не потерять операции. Самая ранняя учётная запись сохраняет своё значение.
+UPDATE "users" u
+SET "username" = u."username" || '_' || left(u."id"::text, 8)
+FROM (
+    SELECT "id", row_number() OVER (PARTITION BY "username" ORDER BY "created_at", "id") AS rn
+    FROM "users"
+) d
+WHERE d."id" = u."id" AND d.rn > 1;
+
+UPDATE "users" u
+SET "email" = regexp_replace(u."email", '@', '+dup' || left(u."id"::text, 8) || '@')
+FROM (
+    SELECT "id", row_number() OVER (PARTITION BY "email" ORDER BY "created_at", "id") AS rn
+    FROM "users"
+) d
+WHERE d."id" = u."id" AND d.rn > 1;
+
+ALTER TABLE
+    "users" ADD CONSTRAINT "users_username_unique" UNIQUE("username");
+ALTER TABLE
+    "users" ADD CONSTRAINT "users_email_unique" UNIQUE("email");
+
+CREATE INDEX "transactions_user_id_date_index" ON "transactions"("user_id", "date");
+
+DELETE FROM "transaction_tags" a
+USING "transaction_tags" b
+WHERE a.ctid < b.ctid
+  AND a."transaction_id" = b."transaction_id"
+  AND a."tag_id" = b."tag_id";
+
+ALTER TABLE
+    "transaction_tags" ADD PRIMARY KEY("transaction_id", "tag_id");
+
+-- Категории и теги без владельца: категорию, которая используется в операциях,
+-- передаём владельцу операции, остальные удаляем.
+UPDATE "categories" c
+SET "user_id" = t."user_id"
+FROM "transactions" t
+WHERE t."category_id" = c."id"
+  AND NOT EXISTS (SELECT 1 FROM "users" u WHERE u."id" = c."user_id");
+
+DELETE FROM "categories" c
+WHERE NOT EXISTS (SELECT 1 FROM "users" u WHERE u."id" = c."user_id");
+
+DELETE FROM "transaction_tags" tt
+USING "tags" t
+WHERE tt."tag_id" = t."id"
+  AND NOT EXISTS (SELECT 1 FROM "users" u WHERE u."id" = t."user_id");
+
+DELETE FROM "tags" t
+WHERE NOT EXISTS (SELECT 1 FROM "users" u WHERE u."id" = t."user_id");
+
+ALTER TABLE
+    "categories" ADD CONSTRAINT "categories_user_id_foreign" FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE CASCADE;
+ALTER TABLE
+    "tags" ADD CONSTRAINT "tags_user_id_foreign" FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE CASCADE;
+
+ALTER TABLE
+    "transaction_tags" DROP CONSTRAINT "transaction_tags_transaction_id_foreign";
+ALTER TABLE
+    "transaction_tags" ADD CONSTRAINT "transaction_tags_transaction_id_foreign" FOREIGN KEY("transaction_id") REFERENCES "transactions"("id") ON DELETE CASCADE;
+ALTER TABLE
+    "transaction_tags" DROP CONSTRAINT "transaction_tags_tag_id_foreign";
+ALTER TABLE
+    "transaction_tags" ADD CONSTRAINT "transaction_tags_tag_id_foreign" FOREIGN KEY("tag_id") REFERENCES "tags"("id") ON DELETE CASCADE;
+
+ALTER TABLE
+    "transactions" DROP CONSTRAINT "transactions_user_id_foreign";
+ALTER TABLE
+    "transactions" ADD CONSTRAINT "transactions_user_id_foreign" FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE CASCADE;
+ALTER TABLE
+    "incomes" DROP CONSTRAINT "incomes_user_id_foreign";
+ALTER TABLE
+    "incomes" ADD CONSTRAINT "incomes_user_id_foreign" FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE CASCADE;
@@ -0,0 +1,15 @@
+-- Email сравниваем без учёта регистра. Дубликаты, которые отличаются только
+-- регистром, переименовываем так же, как в 000002.
+UPDATE "users" u
+SET "email" = regexp_replace(u."email", '@', '+dup' || left(u."id"::text, 8) || '@')
+FROM (
+    SELECT "id", row_number() OVER (PARTITION BY lower(trim("email")) ORDER BY "created_at", "id") AS rn
+    FROM "users"
+) d
+WHERE d."id" = u."id" AND d.rn > 1;
+
+UPDATE "users" SET "email" = lower(trim("email"));
+
+ALTER TABLE
+    "users" DROP CONSTRAINT "users_email_unique";
+CREATE UNIQUE INDEX "users_email_lower_unique" ON "users"(lower("email"));
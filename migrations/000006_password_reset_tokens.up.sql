@@ -0,0 +1,12 @@
+CREATE TABLE "password_reset_tokens"(
+                                        "token_hash" TEXT NOT NULL,
+                                        "user_id" UUID NOT NULL,
+                                        "expires_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL,
+                                        "used_at" TIMESTAMP(0) WITHOUT TIME ZONE NULL,
+                                        "created_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL
+);
+ALTER TABLE
+    "password_reset_tokens" ADD PRIMARY KEY("token_hash");
+ALTER TABLE
+    "password_reset_tokens" ADD CONSTRAINT "password_reset_tokens_user_id_foreign" FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE CASCADE;
+CREATE INDEX "password_reset_tokens_user_id_index" ON "password_reset_tokens"("user_id");
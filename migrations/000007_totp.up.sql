@@ -0,0 +1,16 @@
+ALTER TABLE
+    "users" ADD COLUMN "totp_secret" BYTEA NULL;
+ALTER TABLE
+    "users" ADD COLUMN "totp_enabled_at" TIMESTAMP(0) WITHOUT TIME ZONE NULL;
+ALTER TABLE
+    "users" ADD COLUMN "totp_last_step" BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE "recovery_codes"(
+                                 "user_id" UUID NOT NULL,
+                                 "code_hash" TEXT NOT NULL,
+                                 "used_at" TIMESTAMP(0) WITHOUT TIME ZONE NULL
+);
+ALTER TABLE
+    "recovery_codes" ADD PRIMARY KEY("user_id", "code_hash");
+ALTER TABLE
+    "recovery_codes" ADD CONSTRAINT "recovery_codes_user_id_foreign" FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE CASCADE;
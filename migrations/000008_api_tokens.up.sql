@@ -0,0 +1,17 @@
+CREATE TABLE "api_tokens"(
+                             "id" UUID NOT NULL,
+                             "user_id" UUID NOT NULL,
+                             "name" VARCHAR(64) NOT NULL,
+                             "token_hash" TEXT NOT NULL,
+                             "scopes" TEXT[] NOT NULL,
+                             "expires_at" TIMESTAMP(0) WITHOUT TIME ZONE NULL,
+                             "last_used_at" TIMESTAMP(0) WITHOUT TIME ZONE NULL,
+                             "created_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL
+);
+ALTER TABLE
+    "api_tokens" ADD PRIMARY KEY("id");
+ALTER TABLE
+    "api_tokens" ADD CONSTRAINT "api_tokens_token_hash_unique" UNIQUE("token_hash");
+ALTER TABLE
+    "api_tokens" ADD CONSTRAINT "api_tokens_user_id_foreign" FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE CASCADE;
+CREATE INDEX "api_tokens_user_id_index" ON "api_tokens"("user_id");
@@ -0,0 +1,15 @@
+CREATE TABLE "sessions"(
+                           "id" UUID NOT NULL,
+                           "user_id" UUID NOT NULL,
+                           "user_agent" VARCHAR(512) NOT NULL,
+                           "ip" VARCHAR(45) NOT NULL,
+                           "created_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL,
+                           "last_seen_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL,
+                           "expires_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL,
+                           "revoked_at" TIMESTAMP(0) WITHOUT TIME ZONE NULL
+);
+ALTER TABLE
+    "sessions" ADD PRIMARY KEY("id");
+ALTER TABLE
+    "sessions" ADD CONSTRAINT "sessions_user_id_foreign" FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE CASCADE;
+CREATE INDEX "sessions_user_id_index" ON "sessions"("user_id");
@@ -0,0 +1,2 @@
+ALTER TABLE
+    "users" ADD COLUMN "disabled_at" TIMESTAMP(0) WITHOUT TIME ZONE NULL;
@@ -0,0 +1,28 @@
+-- Операции и категории без автора вернуть пользователю нельзя.
+DELETE FROM "transactions" WHERE "user_id" IS NULL;
+DELETE FROM "transactions" t
+USING "categories" c
+WHERE t."category_id" = c."id" AND c."user_id" IS NULL;
+DELETE FROM "categories" WHERE "user_id" IS NULL;
+
+ALTER TABLE
+    "categories" DROP CONSTRAINT "categories_user_id_foreign";
+ALTER TABLE
+    "categories" ADD CONSTRAINT "categories_user_id_foreign" FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE CASCADE;
+ALTER TABLE
+    "categories" ALTER COLUMN "user_id" SET NOT NULL;
+ALTER TABLE
+    "transactions" DROP CONSTRAINT "transactions_user_id_foreign";
+ALTER TABLE
+    "transactions" ADD CONSTRAINT "transactions_user_id_foreign" FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE CASCADE;
+ALTER TABLE
+    "transactions" ALTER COLUMN "user_id" SET NOT NULL;
+
+ALTER TABLE
+    "categories" DROP COLUMN IF EXISTS "ledger_id";
+ALTER TABLE
+    "transactions" DROP COLUMN IF EXISTS "ledger_id";
+
+DROP TABLE IF EXISTS ledger_invitations CASCADE;
+DROP TABLE IF EXISTS ledger_members CASCADE;
+DROP TABLE IF EXISTS ledgers CASCADE;
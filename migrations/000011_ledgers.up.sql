@@ -0,0 +1,85 @@
+CREATE TABLE "ledgers"(
+                          "id" UUID NOT NULL,
+                          "name" VARCHAR(100) NOT NULL,
+                          "created_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL
+);
+ALTER TABLE
+    "ledgers" ADD PRIMARY KEY("id");
+
+CREATE TABLE "ledger_members"(
+                                 "ledger_id" UUID NOT NULL,
+                                 "user_id" UUID NOT NULL,
+                                 "role" TEXT NOT NULL,
+                                 "created_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL
+);
+ALTER TABLE
+    "ledger_members" ADD PRIMARY KEY("ledger_id", "user_id");
+ALTER TABLE
+    "ledger_members" ADD CONSTRAINT "ledger_members_role_check" CHECK("role" IN ('owner', 'editor', 'viewer'));
+ALTER TABLE
+    "ledger_members" ADD CONSTRAINT "ledger_members_ledger_id_foreign" FOREIGN KEY("ledger_id") REFERENCES "ledgers"("id") ON DELETE CASCADE;
+ALTER TABLE
+    "ledger_members" ADD CONSTRAINT "ledger_members_user_id_foreign" FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE CASCADE;
+CREATE INDEX "ledger_members_user_id_index" ON "ledger_members"("user_id");
+
+CREATE TABLE "ledger_invitations"(
+                                     "id" UUID NOT NULL,
+                                     "ledger_id" UUID NOT NULL,
+                                     "user_id" UUID NOT NULL,
+                                     "role" TEXT NOT NULL,
+                                     "invited_by" UUID NULL,
+                                     "created_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL
+);
+ALTER TABLE
+    "ledger_invitations" ADD PRIMARY KEY("id");
+ALTER TABLE
+    "ledger_invitations" ADD CONSTRAINT "ledger_invitations_ledger_id_user_id_unique" UNIQUE("ledger_id", "user_id");
+ALTER TABLE
+    "ledger_invitations" ADD CONSTRAINT "ledger_invitations_role_check" CHECK("role" IN ('owner', 'editor', 'viewer'));
+ALTER TABLE
+    "ledger_invitations" ADD CONSTRAINT "ledger_invitations_ledger_id_foreign" FOREIGN KEY("ledger_id") REFERENCES "ledgers"("id") ON DELETE CASCADE;
+ALTER TABLE
+    "ledger_invitations" ADD CONSTRAINT "ledger_invitations_user_id_foreign" FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE CASCADE;
+ALTER TABLE
+    "ledger_invitations" ADD CONSTRAINT "ledger_invitations_invited_by_foreign" FOREIGN KEY("invited_by") REFERENCES "users"("id") ON DELETE SET NULL;
+CREATE INDEX "ledger_invitations_user_id_index" ON "ledger_invitations"("user_id");
+
+-- У каждого пользователя есть личная книга с тем же id, что и у него самого.
+-- В неё переносятся все существующие операции и категории.
+INSERT INTO "ledgers" ("id", "name", "created_at")
+SELECT "id", 'Personal', NOW() FROM "users";
+INSERT INTO "ledger_members" ("ledger_id", "user_id", "role", "created_at")
+SELECT "id", "id", 'owner', NOW() FROM "users";
+
+ALTER TABLE
+    "transactions" ADD COLUMN "ledger_id" UUID NULL;
+UPDATE "transactions" SET "ledger_id" = "user_id";
+ALTER TABLE
+    "transactions" ALTER COLUMN "ledger_id" SET NOT NULL;
+ALTER TABLE
+    "transactions" ADD CONSTRAINT "transactions_ledger_id_foreign" FOREIGN KEY("ledger_id") REFERENCES "ledgers"("id") ON DELETE CASCADE;
+CREATE INDEX "transactions_ledger_id_date_index" ON "transactions"("ledger_id", "date");
+
+ALTER TABLE
+    "categories" ADD COLUMN "ledger_id" UUID NULL;
+UPDATE "categories" SET "ledger_id" = "user_id";
+ALTER TABLE
+    "categories" ALTER COLUMN "ledger_id" SET NOT NULL;
+ALTER TABLE
+    "categories" ADD CONSTRAINT "categories_ledger_id_foreign" FOREIGN KEY("ledger_id") REFERENCES "ledgers"("id") ON DELETE CASCADE;
+CREATE INDEX "categories_ledger_id_index" ON "categories"("ledger_id");
+
+-- user_id теперь означает автора. Операции и категории общей книги
+-- остаются в ней, когда автор удаляет свой аккаунт.
+ALTER TABLE
+    "transactions" ALTER COLUMN "user_id" DROP NOT NULL;
+ALTER TABLE
+    "transactions" DROP CONSTRAINT "transactions_user_id_foreign";
+ALTER TABLE
+    "transactions" ADD CONSTRAINT "transactions_user_id_foreign" FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE SET NULL;
+ALTER TABLE
+    "categories" ALTER COLUMN "user_id" DROP NOT NULL;
+ALTER TABLE
+    "categories" DROP CONSTRAINT "categories_user_id_foreign";
+ALTER TABLE
+    "categories" ADD CONSTRAINT "categories_user_id_foreign" FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE SET NULL;
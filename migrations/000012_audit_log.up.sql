@@ -0,0 +1,36 @@
+CREATE TABLE "audit_log"(
+                            "id" BIGSERIAL NOT NULL,
+                            "actor_id" UUID NULL,
+                            "user_id" UUID NULL,
+                            "ledger_id" UUID NULL,
+                            "entity" TEXT NOT NULL,
+                            "entity_id" TEXT NOT NULL,
+                            "action" TEXT NOT NULL,
+                            "before" JSONB NULL,
+                            "after" JSONB NULL,
+                            "request_id" TEXT NOT NULL,
+                            "ip" VARCHAR(45) NOT NULL,
+                            "created_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL
+);
+ALTER TABLE
+    "audit_log" ADD PRIMARY KEY("id");
+ALTER TABLE
+    "audit_log" ADD CONSTRAINT "audit_log_action_check" CHECK("action" IN ('create', 'update', 'delete'));
+CREATE INDEX "audit_log_user_id_index" ON "audit_log"("user_id", "id");
+CREATE INDEX "audit_log_actor_id_index" ON "audit_log"("actor_id", "id");
+CREATE INDEX "audit_log_ledger_id_index" ON "audit_log"("ledger_id", "id");
+
+-- Журнал только дополняется. Внешних ключей нет намеренно: записи
+-- остаются и после удаления пользователя или книги.
+CREATE FUNCTION "audit_log_append_only"() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "audit_log_no_update_delete"
+    BEFORE UPDATE OR DELETE ON "audit_log"
+    FOR EACH ROW EXECUTE FUNCTION "audit_log_append_only"();
+CREATE TRIGGER "audit_log_no_truncate"
+    BEFORE TRUNCATE ON "audit_log"
+    FOR EACH STATEMENT EXECUTE FUNCTION "audit_log_append_only"();
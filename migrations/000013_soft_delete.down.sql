@@ -0,0 +1,22 @@
+-- Без deleted_at корзину не отличить от живых данных, поэтому очищаем её досрочно.
+DELETE FROM "transactions" WHERE "deleted_at" IS NOT NULL;
+DELETE FROM "incomes" WHERE "deleted_at" IS NOT NULL;
+DELETE FROM "categories" c
+WHERE c."deleted_at" IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM "transactions" t WHERE t."category_id" = c."id");
+DELETE FROM "tags" WHERE "deleted_at" IS NOT NULL;
+
+-- Журнал нельзя менять, поэтому старые записи о восстановлении не проверяем.
+ALTER TABLE
+    "audit_log" DROP CONSTRAINT "audit_log_action_check";
+ALTER TABLE
+    "audit_log" ADD CONSTRAINT "audit_log_action_check" CHECK("action" IN ('create', 'update', 'delete')) NOT VALID;
+
+ALTER TABLE
+    "tags" DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE
+    "categories" DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE
+    "incomes" DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE
+    "transactions" DROP COLUMN IF EXISTS "deleted_at";
@@ -0,0 +1,19 @@
+ALTER TABLE
+    "transactions" ADD COLUMN "deleted_at" TIMESTAMP(0) WITHOUT TIME ZONE NULL;
+ALTER TABLE
+    "incomes" ADD COLUMN "deleted_at" TIMESTAMP(0) WITHOUT TIME ZONE NULL;
+ALTER TABLE
+    "categories" ADD COLUMN "deleted_at" TIMESTAMP(0) WITHOUT TIME ZONE NULL;
+ALTER TABLE
+    "tags" ADD COLUMN "deleted_at" TIMESTAMP(0) WITHOUT TIME ZONE NULL;
+
+ALTER TABLE
+    "audit_log" DROP CONSTRAINT "audit_log_action_check";
+ALTER TABLE
+    "audit_log" ADD CONSTRAINT "audit_log_action_check" CHECK("action" IN ('create', 'update', 'delete', 'restore'));
+
+-- Корзина и очистка читают только удалённые строки, их обычно немного.
+CREATE INDEX "transactions_deleted_at_index" ON "transactions"("deleted_at") WHERE "deleted_at" IS NOT NULL;
+CREATE INDEX "incomes_deleted_at_index" ON "incomes"("deleted_at") WHERE "deleted_at" IS NOT NULL;
+CREATE INDEX "categories_deleted_at_index" ON "categories"("deleted_at") WHERE "deleted_at" IS NOT NULL;
+CREATE INDEX "tags_deleted_at_index" ON "tags"("deleted_at") WHERE "deleted_at" IS NOT NULL;
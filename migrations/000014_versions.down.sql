@@ -0,0 +1,8 @@
+ALTER TABLE
+    "users" DROP COLUMN IF EXISTS "version";
+ALTER TABLE
+    "ledgers" DROP COLUMN IF EXISTS "version";
+ALTER TABLE
+    "categories" DROP COLUMN IF EXISTS "version";
+ALTER TABLE
+    "transactions" DROP COLUMN IF EXISTS "version";
@@ -0,0 +1,9 @@
+-- Версия увеличивается при каждом изменении строки и служит ETag ресурса.
+ALTER TABLE
+    "transactions" ADD COLUMN "version" BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE
+    "categories" ADD COLUMN "version" BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE
+    "ledgers" ADD COLUMN "version" BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE
+    "users" ADD COLUMN "version" BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS "tags_name_trgm_index";
+DROP INDEX IF EXISTS "categories_name_trgm_index";
+DROP INDEX IF EXISTS "transactions_comment_trgm_index";
+DROP INDEX IF EXISTS "transactions_search_vector_index";
+
+ALTER TABLE
+    "transactions" DROP COLUMN IF EXISTS "search_vector";
+
+-- pg_trgm мог быть установлен и до этой миграции, поэтому расширение не удаляем.
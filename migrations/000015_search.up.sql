@@ -0,0 +1,13 @@
+CREATE EXTENSION IF NOT EXISTS "pg_trgm";
+
+-- Язык комментария неизвестен, поэтому индексируем его в обеих конфигурациях.
+ALTER TABLE
+    "transactions" ADD COLUMN "search_vector" TSVECTOR GENERATED ALWAYS AS (
+        to_tsvector('russian', "comment") || to_tsvector('english', "comment")
+    ) STORED;
+
+CREATE INDEX "transactions_search_vector_index" ON "transactions" USING GIN("search_vector");
+-- Триграммы находят слова с опечатками, которые не ловит полнотекстовый поиск.
+CREATE INDEX "transactions_comment_trgm_index" ON "transactions" USING GIN("comment" gin_trgm_ops);
+CREATE INDEX "categories_name_trgm_index" ON "categories" USING GIN("name" gin_trgm_ops);
+CREATE INDEX "tags_name_trgm_index" ON "tags" USING GIN("name" gin_trgm_ops);
@@ -0,0 +1,34 @@
+CREATE TABLE "rules"(
+                        "id" UUID NOT NULL,
+                        "ledger_id" UUID NOT NULL,
+                        "user_id" UUID NULL,
+                        "name" TEXT NOT NULL,
+                        "priority" INTEGER NOT NULL,
+                        "enabled" BOOLEAN NOT NULL DEFAULT TRUE,
+                        "comment_contains" TEXT NULL,
+                        "comment_regex" TEXT NULL,
+                        "amount_min" DOUBLE PRECISION NULL,
+                        "amount_max" DOUBLE PRECISION NULL,
+                        "author_id" UUID NULL,
+                        "weekdays" SMALLINT[] NOT NULL DEFAULT '{}',
+                        "category_id" UUID NULL,
+                        "tag_ids" UUID[] NOT NULL DEFAULT '{}',
+                        "created_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL,
+                        "version" BIGINT NOT NULL DEFAULT 1
+);
+ALTER TABLE
+    "rules" ADD PRIMARY KEY("id");
+ALTER TABLE
+    "rules" ADD CONSTRAINT "rules_ledger_id_foreign" FOREIGN KEY("ledger_id") REFERENCES "ledgers"("id") ON DELETE CASCADE;
+-- user_id — кто последним менял правило, его теги правило и ставит.
+-- Правило принадлежит книге, поэтому без автора оно продолжает работать.
+ALTER TABLE
+    "rules" ADD CONSTRAINT "rules_user_id_foreign" FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE SET NULL;
+-- Условие на удалённого автора больше никогда не выполнится.
+ALTER TABLE
+    "rules" ADD CONSTRAINT "rules_author_id_foreign" FOREIGN KEY("author_id") REFERENCES "users"("id") ON DELETE CASCADE;
+ALTER TABLE
+    "rules" ADD CONSTRAINT "rules_category_id_foreign" FOREIGN KEY("category_id") REFERENCES "categories"("id") ON DELETE SET NULL;
+ALTER TABLE
+    "rules" ADD CONSTRAINT "rules_amount_range_check" CHECK("amount_min" IS NULL OR "amount_max" IS NULL OR "amount_min" <= "amount_max");
+CREATE INDEX "rules_ledger_id_priority_index" ON "rules"("ledger_id", "priority");
@@ -0,0 +1,16 @@
+DELETE FROM "incomes" WHERE "user_id" IS NULL;
+ALTER TABLE
+    "incomes" DROP CONSTRAINT IF EXISTS "incomes_user_id_foreign";
+ALTER TABLE
+    "incomes" ADD CONSTRAINT "incomes_user_id_foreign" FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE CASCADE;
+ALTER TABLE
+    "incomes" ALTER COLUMN "user_id" SET NOT NULL;
+DROP INDEX IF EXISTS "incomes_goal_id_index";
+ALTER TABLE
+    "incomes" DROP CONSTRAINT IF EXISTS "incomes_goal_id_foreign";
+ALTER TABLE
+    "incomes" DROP COLUMN IF EXISTS "goal_id";
+ALTER TABLE
+    "incomes" ALTER COLUMN "amount" TYPE BIGINT USING round("amount");
+
+DROP TABLE IF EXISTS "goals";
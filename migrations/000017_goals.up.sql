@@ -0,0 +1,42 @@
+CREATE TABLE "goals"(
+                        "id" UUID NOT NULL,
+                        "ledger_id" UUID NOT NULL,
+                        "user_id" UUID NULL,
+                        "name" TEXT NOT NULL,
+                        "target_amount" DOUBLE PRECISION NOT NULL,
+                        "target_date" DATE NULL,
+                        "start_date" DATE NOT NULL,
+                        "category_id" UUID NULL,
+                        "created_at" TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL,
+                        "version" BIGINT NOT NULL DEFAULT 1
+);
+ALTER TABLE
+    "goals" ADD PRIMARY KEY("id");
+ALTER TABLE
+    "goals" ADD CONSTRAINT "goals_ledger_id_foreign" FOREIGN KEY("ledger_id") REFERENCES "ledgers"("id") ON DELETE CASCADE;
+ALTER TABLE
+    "goals" ADD CONSTRAINT "goals_user_id_foreign" FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE SET NULL;
+ALTER TABLE
+    "goals" ADD CONSTRAINT "goals_category_id_foreign" FOREIGN KEY("category_id") REFERENCES "categories"("id") ON DELETE SET NULL;
+ALTER TABLE
+    "goals" ADD CONSTRAINT "goals_target_amount_check" CHECK("target_amount" > 0);
+ALTER TABLE
+    "goals" ADD CONSTRAINT "goals_target_date_check" CHECK("target_date" IS NULL OR "target_date" > "start_date");
+CREATE INDEX "goals_ledger_id_index" ON "goals"("ledger_id");
+
+-- Взносы в цель записываются доходами. Суммы операций дробные, доходы приводим к тому же типу.
+ALTER TABLE
+    "incomes" ALTER COLUMN "amount" TYPE DOUBLE PRECISION;
+ALTER TABLE
+    "incomes" ADD COLUMN "goal_id" UUID NULL;
+-- Доход остаётся доходом и после удаления цели.
+ALTER TABLE
+    "incomes" ADD CONSTRAINT "incomes_goal_id_foreign" FOREIGN KEY("goal_id") REFERENCES "goals"("id") ON DELETE SET NULL;
+CREATE INDEX "incomes_goal_id_index" ON "incomes"("goal_id") WHERE "goal_id" IS NOT NULL;
+-- Взносы в цели общих книг принадлежат книге и переживают удаление автора.
+ALTER TABLE
+    "incomes" ALTER COLUMN "user_id" DROP NOT NULL;
+ALTER TABLE
+    "incomes" DROP CONSTRAINT "incomes_user_id_foreign";
+ALTER TABLE
+    "incomes" ADD CONSTRAINT "incomes_user_id_foreign" FOREIGN KEY("user_id") REFERENCES "users"("id") ON DELETE SET NULL;
@@ -0,0 +1,54 @@
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+)
+
+type Encryptor interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// AESEncryptor encrypts data with AES-GCM. The random nonce is stored
+// in front of the ciphertext.
+type AESEncryptor struct {
+	aead cipher.AEAD
+}
+
+// NewAESEncryptor expects a 16, 24 or 32 byte key.
+func NewAESEncryptor(key []byte) (*AESEncryptor, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AESEncryptor{aead: aead}, nil
+}
+
+// Encrypt seals plaintext with a fresh random nonce.
+func (e *AESEncryptor) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return e.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt opens data produced by Encrypt.
+func (e *AESEncryptor) Decrypt(ciphertext []byte) ([]byte, error) {
+	nonceSize := e.aead.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, errors.New("ciphertext is too short")
+	}
+
+	return e.aead.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], nil)
+}
@@ -0,0 +1,100 @@
+package encrypt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func newTestEncryptor(t *testing.T) *AESEncryptor {
+	t.Helper()
+
+	e, err := NewAESEncryptor(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return e
+}
+
+func TestRoundTrip(t *testing.T) {
+	e := newTestEncryptor(t)
+	plaintext := []byte("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+
+	ciphertext, err := e.Encrypt(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(ciphertext, plaintext) {
+		t.Fatal("ciphertext contains the plaintext")
+	}
+
+	decrypted, err := e.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("decrypted = %q, want %q", decrypted, plaintext)
+	}
+}
+
+func TestFreshNonce(t *testing.T) {
+	e := newTestEncryptor(t)
+
+	a, err := e.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := e.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Equal(a, b) {
+		t.Error("same plaintext encrypted to the same ciphertext")
+	}
+}
+
+func TestRejectsTampering(t *testing.T) {
+	e := newTestEncryptor(t)
+
+	ciphertext, err := e.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Портим по байту в nonce, в самих данных и в теге.
+	for _, i := range []int{0, len(ciphertext) / 2, len(ciphertext) - 1} {
+		tampered := bytes.Clone(ciphertext)
+		tampered[i] ^= 0x01
+
+		if _, err := e.Decrypt(tampered); err == nil {
+			t.Errorf("tampered byte %d accepted", i)
+		}
+	}
+
+	if _, err := e.Decrypt(ciphertext[:e.aead.NonceSize()-1]); err == nil {
+		t.Error("short ciphertext accepted")
+	}
+}
+
+func TestRejectsOtherKey(t *testing.T) {
+	ciphertext, err := newTestEncryptor(t).Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := NewAESEncryptor(bytes.Repeat([]byte{8}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := other.Decrypt(ciphertext); err == nil {
+		t.Error("ciphertext decrypted with another key")
+	}
+}
+
+func TestInvalidKeySize(t *testing.T) {
+	if _, err := NewAESEncryptor(make([]byte, 10)); err == nil {
+		t.Error("10 byte key accepted")
+	}
+}